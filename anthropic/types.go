@@ -0,0 +1,151 @@
+package anthropic
+
+import "encoding/json"
+
+// MessagesRequest is an Anthropic Messages API request
+type MessagesRequest struct {
+	Model         string                 `json:"model"`
+	MaxTokens     int                    `json:"max_tokens"`
+	System        Content                `json:"system,omitempty"`
+	Messages      []Message              `json:"messages"`
+	StopSequences []string               `json:"stop_sequences,omitempty"`
+	Stream        bool                   `json:"stream,omitempty"`
+	Temperature   *float64               `json:"temperature,omitempty"`
+	TopP          *float64               `json:"top_p,omitempty"`
+	TopK          *int                   `json:"top_k,omitempty"`
+	Tools         []Tool                 `json:"tools,omitempty"`
+	ToolChoice    *ToolChoice            `json:"tool_choice,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Message is one conversation turn
+type Message struct {
+	Role    string  `json:"role"` // user or assistant
+	Content Content `json:"content"`
+}
+
+// Content is message content, sent either as a plain string or a list of blocks
+type Content []ContentBlock
+
+// UnmarshalJSON accepts a string as a single text block
+func (c *Content) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = Content{{Type: "text", Text: text}}
+		return nil
+	}
+
+	var blocks []ContentBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return err
+	}
+	*c = blocks
+	return nil
+}
+
+// ContentBlock is a block of request content: text, image, tool_use or tool_result
+type ContentBlock struct {
+	Type      string                 `json:"type"`
+	Text      string                 `json:"text,omitempty"`
+	Source    *ImageSource           `json:"source,omitempty"`
+	ID        string                 `json:"id,omitempty"`          // tool_use
+	Name      string                 `json:"name,omitempty"`        // tool_use
+	Input     map[string]interface{} `json:"input,omitempty"`       // tool_use
+	ToolUseID string                 `json:"tool_use_id,omitempty"` // tool_result
+	Content   Content                `json:"content,omitempty"`     // tool_result
+	IsError   bool                   `json:"is_error,omitempty"`    // tool_result
+}
+
+// ImageSource holds image data for an image block
+type ImageSource struct {
+	Type      string `json:"type"` // base64 or url
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// Tool is a client tool the model may call
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// ToolChoice controls tool use: auto, any, tool or none
+type ToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// MessagesResponse is an Anthropic Messages API response
+type MessagesResponse struct {
+	ID           string        `json:"id"`
+	Type         string        `json:"type"`
+	Role         string        `json:"role"`
+	Model        string        `json:"model"`
+	Content      []interface{} `json:"content"` // TextBlock and ToolUseBlock values
+	StopReason   *string       `json:"stop_reason"`
+	StopSequence *string       `json:"stop_sequence"`
+	Usage        Usage         `json:"usage"`
+}
+
+// TextBlock is a text block of response content
+type TextBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// ToolUseBlock is a tool call in response content
+type ToolUseBlock struct {
+	Type  string                 `json:"type"`
+	ID    string                 `json:"id"`
+	Name  string                 `json:"name"`
+	Input map[string]interface{} `json:"input"`
+}
+
+// Usage reports token counts
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// StreamEvent is the data of a named SSE event; the fields used depend on Type
+type StreamEvent struct {
+	Type         string            `json:"type"`
+	Message      *MessagesResponse `json:"message,omitempty"`       // message_start
+	Index        *int              `json:"index,omitempty"`         // content_block_*
+	ContentBlock interface{}       `json:"content_block,omitempty"` // content_block_start
+	Delta        interface{}       `json:"delta,omitempty"`         // content_block_delta, message_delta
+	Usage        *Usage            `json:"usage,omitempty"`         // message_delta
+	Error        *ErrorDetail      `json:"error,omitempty"`         // error
+}
+
+// TextDelta appends text to a text block
+type TextDelta struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// InputJSONDelta appends to the JSON input of a tool_use block
+type InputJSONDelta struct {
+	Type        string `json:"type"`
+	PartialJSON string `json:"partial_json"`
+}
+
+// MessageDelta carries the final stop reason
+type MessageDelta struct {
+	StopReason   *string `json:"stop_reason"`
+	StopSequence *string `json:"stop_sequence"`
+}
+
+// ErrorResponse is the Anthropic error format
+type ErrorResponse struct {
+	Type  string      `json:"type"`
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes an error
+type ErrorDetail struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,109 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"ollama2openai/pkg/fsutil"
+)
+
+// FileStore keeps batch jobs as JSON files in a local directory
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFileStore creates a file-backed batch store
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create batch directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save writes a job to disk
+func (s *FileStore) Save(job *Job) error {
+	if !fsutil.ValidID(job.Batch.ID) {
+		return fmt.Errorf("invalid batch id: %q", job.Batch.ID)
+	}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := filepath.Join(s.dir, job.Batch.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write batch: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to rename batch: %w", err)
+	}
+	return nil
+}
+
+// Get reads a job from disk
+func (s *FileStore) Get(id string) (*Job, error) {
+	if !fsutil.ValidID(id) {
+		return nil, ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return readJob(filepath.Join(s.dir, id+".json"))
+}
+
+// List reads all jobs from disk, oldest first
+func (s *FileStore) List() ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch directory: %w", err)
+	}
+
+	var jobs []*Job
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		job, err := readJob(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Batch.CreatedAt < jobs[j].Batch.CreatedAt
+	})
+	return jobs, nil
+}
+
+func readJob(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read batch: %w", err)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode batch: %w", err)
+	}
+	return &job, nil
+}
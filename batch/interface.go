@@ -0,0 +1,32 @@
+package batch
+
+import (
+	"errors"
+
+	"ollama2openai/openai"
+)
+
+// ErrNotFound is returned when a batch does not exist
+var ErrNotFound = errors.New("not found")
+
+// Job is a batch together with the API key alias that created it
+type Job struct {
+	Batch openai.Batch `json:"batch"`
+	Alias string       `json:"alias"`
+}
+
+// Store defines the interface for persisting batch jobs
+// This allows for different implementations (file, database, etc.)
+type Store interface {
+	// Save stores a job, replacing any existing one with the same ID
+	Save(job *Job) error
+
+	// Get returns the job with the given ID, or ErrNotFound
+	Get(id string) (*Job, error)
+
+	// List returns all jobs, oldest first
+	List() ([]*Job, error)
+}
+
+// Ensure implementations satisfy Store
+var _ Store = (*FileStore)(nil)
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// disk stores embeddings as raw little-endian float64 files, laid out as
+// <dir>/<model hash>/<digest>/<key hash>.bin so a model's stale digests
+// can be removed as whole directories
+type disk struct {
+	dir string
+}
+
+func newDisk(dir string) (*disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create embedding cache directory: %w", err)
+	}
+	return &disk{dir: dir}, nil
+}
+
+func (d *disk) get(key Key) ([]float64, bool) {
+	data, err := os.ReadFile(d.path(key))
+	if err != nil || len(data)%8 != 0 {
+		return nil, false
+	}
+
+	embedding := make([]float64, len(data)/8)
+	for i := range embedding {
+		embedding[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[8*i:]))
+	}
+	return embedding, true
+}
+
+func (d *disk) put(key Key, embedding []float64) error {
+	path := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data := make([]byte, 8*len(embedding))
+	for i, x := range embedding {
+		binary.LittleEndian.PutUint64(data[8*i:], math.Float64bits(x))
+	}
+
+	// Write to a unique temporary file so concurrent writers never interleave
+	tmp, err := os.CreateTemp(filepath.Dir(path), "*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// invalidate removes digest directories for model other than digest
+func (d *disk) invalidate(model, digest string) {
+	modelDir := filepath.Join(d.dir, modelDirName(model))
+	entries, err := os.ReadDir(modelDir)
+	if err != nil {
+		return
+	}
+
+	keep := digestDirName(digest)
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != keep {
+			os.RemoveAll(filepath.Join(modelDir, e.Name()))
+		}
+	}
+}
+
+func (d *disk) path(key Key) string {
+	return filepath.Join(d.dir, modelDirName(key.Model), digestDirName(key.Digest), key.Hash+".bin")
+}
+
+// modelDirName hashes model names, which may contain '/' and ':'
+func modelDirName(model string) string {
+	sum := sha256.Sum256([]byte(model))
+	return hex.EncodeToString(sum[:8])
+}
+
+// digestDirName turns a digest such as "sha256:abc" into a safe directory name
+func digestDirName(digest string) string {
+	if digest == "" {
+		return "unknown"
+	}
+	return strings.NewReplacer(":", "-", "/", "-", "\\", "-", "..", "-").Replace(digest)
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Key identifies a cached embedding by model, model digest and input content
+type Key struct {
+	Model  string
+	Digest string
+	Hash   string // SHA-256 of the model, digest and normalized input
+}
+
+// NewKey builds a content-addressed key for an embedding input
+func NewKey(model, digest, input string) Key {
+	h := sha256.New()
+	h.Write([]byte(model))
+	h.Write([]byte{0})
+	h.Write([]byte(digest))
+	h.Write([]byte{0})
+	h.Write([]byte(normalizeInput(input)))
+
+	return Key{
+		Model:  model,
+		Digest: digest,
+		Hash:   hex.EncodeToString(h.Sum(nil)),
+	}
+}
+
+// normalizeInput unifies line endings so copies of a document differing only
+// in them share a cache entry; other whitespace is kept since it changes the
+// tokens and so the embedding
+func normalizeInput(input string) string {
+	return strings.ReplaceAll(input, "\r\n", "\n")
+}
+
+// Stats holds cache hit metrics
+type Stats struct {
+	MemoryHits    int64 `json:"memory_hits"`
+	DiskHits      int64 `json:"disk_hits"`
+	Misses        int64 `json:"misses"`
+	MemoryEntries int   `json:"memory_entries"`
+	Invalidations int64 `json:"invalidations"`
+}
+
+// EmbeddingCache defines the interface for caching embeddings
+// This allows for different implementations (in-memory, disk, Redis, etc.)
+type EmbeddingCache interface {
+	// Get returns the cached embedding for key
+	Get(key Key) ([]float64, bool)
+
+	// Put stores an embedding
+	Put(key Key, embedding []float64)
+
+	// Invalidate drops all entries for model that were not produced by digest
+	Invalidate(model, digest string)
+
+	// Stats returns hit metrics
+	Stats() Stats
+}
+
+// Ensure TieredCache implements EmbeddingCache
+var _ EmbeddingCache = (*TieredCache)(nil)
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru is a fixed-size in-memory cache evicting the least recently used entry
+type lru struct {
+	mu      sync.Mutex
+	max     int
+	order   *list.List // Front is most recently used
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       Key
+	embedding []float64
+}
+
+func newLRU(max int) *lru {
+	return &lru{
+		max:     max,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *lru) get(key Key) ([]float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key.Hash]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).embedding, true
+}
+
+func (c *lru) put(key Key, embedding []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key.Hash]; ok {
+		el.Value.(*lruEntry).embedding = embedding
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key.Hash] = c.order.PushFront(&lruEntry{key: key, embedding: embedding})
+
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key.Hash)
+	}
+}
+
+// invalidate removes entries for model with a different digest
+func (c *lru) invalidate(model, digest string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		entry := el.Value.(*lruEntry)
+		if entry.key.Model == model && entry.key.Digest != digest {
+			c.order.Remove(el)
+			delete(c.entries, entry.key.Hash)
+		}
+		el = next
+	}
+}
+
+func (c *lru) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
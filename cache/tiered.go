@@ -0,0 +1,78 @@
+package cache
+
+import "sync/atomic"
+
+// TieredCache is an EmbeddingCache with an in-memory LRU tier backed by an optional disk tier
+type TieredCache struct {
+	memory *lru
+	disk   *disk // nil when the disk tier is disabled
+
+	memoryHits    atomic.Int64
+	diskHits      atomic.Int64
+	misses        atomic.Int64
+	invalidations atomic.Int64
+}
+
+// NewTieredCache creates a cache holding up to maxEntries embeddings in memory
+// If diskPath is not empty, entries are also persisted under that directory
+func NewTieredCache(maxEntries int, diskPath string) (*TieredCache, error) {
+	c := &TieredCache{memory: newLRU(maxEntries)}
+
+	if diskPath != "" {
+		d, err := newDisk(diskPath)
+		if err != nil {
+			return nil, err
+		}
+		c.disk = d
+	}
+
+	return c, nil
+}
+
+// Get looks up the memory tier first, then the disk tier, promoting disk hits to memory
+func (c *TieredCache) Get(key Key) ([]float64, bool) {
+	if embedding, ok := c.memory.get(key); ok {
+		c.memoryHits.Add(1)
+		return embedding, true
+	}
+
+	if c.disk != nil {
+		if embedding, ok := c.disk.get(key); ok {
+			c.diskHits.Add(1)
+			c.memory.put(key, embedding)
+			return embedding, true
+		}
+	}
+
+	c.misses.Add(1)
+	return nil, false
+}
+
+// Put stores an embedding in every tier
+// Disk write failures are ignored since the entry is still cached in memory
+func (c *TieredCache) Put(key Key, embedding []float64) {
+	c.memory.put(key, embedding)
+	if c.disk != nil {
+		c.disk.put(key, embedding)
+	}
+}
+
+// Invalidate drops entries for model produced by any other digest
+func (c *TieredCache) Invalidate(model, digest string) {
+	c.invalidations.Add(1)
+	c.memory.invalidate(model, digest)
+	if c.disk != nil {
+		c.disk.invalidate(model, digest)
+	}
+}
+
+// Stats returns hit metrics
+func (c *TieredCache) Stats() Stats {
+	return Stats{
+		MemoryHits:    c.memoryHits.Load(),
+		DiskHits:      c.diskHits.Load(),
+		Misses:        c.misses.Load(),
+		MemoryEntries: c.memory.len(),
+		Invalidations: c.invalidations.Load(),
+	}
+}
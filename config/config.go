@@ -3,19 +3,149 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+	"ollama2openai/ollama"
 )
 
 // Config represents the application configuration
 type Config struct {
-	Host      string            `yaml:"host"`
-	Port      int               `yaml:"port"`
-	OllamaURL string            `yaml:"ollama_url"`
-	APIKeys   map[string]string `yaml:"api_keys"`
-	Timeout   int               `yaml:"timeout"`
-	LogLevel  string            `yaml:"log_level"`
+	Host             string              `yaml:"host"`
+	Port             int                 `yaml:"port"`
+	OllamaURL        string              `yaml:"ollama_url"`
+	OllamaBackends   []BackendConfig     `yaml:"ollama_backends"` // Pool of Ollama servers; defaults to ollama_url alone
+	LoadBalancing    LoadBalancingConfig `yaml:"load_balancing"`
+	HealthCheck      HealthCheckConfig   `yaml:"health_check"`
+	Retry            RetryConfig         `yaml:"retry"`
+	Providers        []ProviderConfig    `yaml:"providers"`       // Upstreams for specific models; other models go to ollama_url
+	ModelFallbacks   []string            `yaml:"model_fallbacks"` // Chains such as "qwen2.5:72b -> qwen2.5:32b -> llama3", tried in order
+	APIKeys          map[string]string   `yaml:"api_keys"`
+	AdminAliases     []string            `yaml:"admin_aliases"` // Aliases allowed to manage models through the native Ollama API
+	Timeout          int                 `yaml:"timeout"`
+	LogLevel         string              `yaml:"log_level"`
+	Responses        ResponsesConfig     `yaml:"responses"`
+	Embeddings       EmbeddingsConfig    `yaml:"embeddings"`
+	Tokenizers       map[string]string   `yaml:"tokenizers"` // Model -> tokenizer.json or tiktoken file for token-array inputs
+	Rerank           RerankConfig        `yaml:"rerank"`
+	VectorStores     VectorStoresConfig  `yaml:"vector_stores"`
+	Files            FilesConfig         `yaml:"files"`
+	Batches          BatchesConfig       `yaml:"batches"`
+	Moderation       ModerationConfig    `yaml:"moderation"`
+	AzureDeployments map[string]string   `yaml:"azure_deployments"` // Azure deployment name -> Ollama model
+}
+
+// ModerationConfig configures the guard model behind /v1/moderations
+type ModerationConfig struct {
+	Model string `yaml:"model"` // Guard model used for OpenAI moderation model names
+
+	// Guard category codes (e.g. Llama Guard's "S1") -> OpenAI moderation categories
+	Categories map[string][]string `yaml:"categories"`
+}
+
+// BackendConfig describes an Ollama server in the backend pool
+type BackendConfig struct {
+	URL    string `yaml:"url"`
+	Weight int    `yaml:"weight"` // Relative share of requests, defaults to 1
+}
+
+// LoadBalancingConfig configures how requests are spread over the Ollama backends
+type LoadBalancingConfig struct {
+	Strategy         string `yaml:"strategy"`          // "round_robin" (weighted) or "least_in_flight"
+	InventoryRefresh int    `yaml:"inventory_refresh"` // Seconds between refreshes of each backend's model list
+	LoadedRefresh    int    `yaml:"loaded_refresh"`    // Seconds between polls of each backend's loaded models (/api/ps)
+	StickySessions   bool   `yaml:"sticky_sessions"`   // Pin conversations to the backend holding their KV cache
+	SessionHeader    string `yaml:"session_header"`    // Header naming a session explicitly, "X-Session-ID" by default
+	SessionTTL       int    `yaml:"session_ttl"`       // Seconds a pin outlives the conversation's last turn
+}
+
+// HealthCheckConfig configures the probes and circuit breakers of the Ollama backends
+type HealthCheckConfig struct {
+	Interval         int `yaml:"interval"`          // Seconds between probes of each backend
+	FailureThreshold int `yaml:"failure_threshold"` // Consecutive failures that open a backend's circuit
+	OpenDuration     int `yaml:"open_duration"`     // Seconds an open circuit fails fast before a half-open probe
+}
+
+// RetryConfig configures retries of Ollama requests that failed to connect or
+// returned a server error, preferring another backend for each attempt
+type RetryConfig struct {
+	MaxAttempts    int `yaml:"max_attempts"`    // Attempts per request including the first, 1 disables retries
+	InitialBackoff int `yaml:"initial_backoff"` // Milliseconds before the first retry, doubled for each further one
+	MaxBackoff     int `yaml:"max_backoff"`     // Upper bound in milliseconds of the backoff
+}
+
+// ProviderConfig describes an upstream inference server and the models it serves
+type ProviderConfig struct {
+	Name   string   `yaml:"name"`    // Used in logs
+	Type   string   `yaml:"type"`    // "ollama" or "openai" (OpenAI-compatible, e.g. llama.cpp server or vLLM)
+	URL    string   `yaml:"url"`     // Base URL; for "openai" including the /v1 prefix
+	APIKey string   `yaml:"api_key"` // Sent as a Bearer token to "openai" upstreams
+	Models []string `yaml:"models"`
+}
+
+// BatchesConfig configures the background execution of /v1/batches jobs
+type BatchesConfig struct {
+	Path        string `yaml:"path"`         // Directory for batch jobs and in-progress results
+	Workers     int    `yaml:"workers"`      // Batch requests executed concurrently
+	BusyWorkers int    `yaml:"busy_workers"` // Batch requests executed concurrently while interactive requests are running
+}
+
+// FilesConfig configures storage for files uploaded through /v1/files
+type FilesConfig struct {
+	Path      string `yaml:"path"`        // Directory for uploaded files
+	MaxSizeMB int    `yaml:"max_size_mb"` // Largest accepted upload in megabytes
+}
+
+// VectorStoresConfig configures the local vector stores used by /v1/vector_stores and file_search
+type VectorStoresConfig struct {
+	Path           string `yaml:"path"`            // Directory for the on-disk index
+	EmbeddingModel string `yaml:"embedding_model"` // Default model for new vector stores
+	ChunkSize      int    `yaml:"chunk_size"`      // Default tokens per chunk
+	ChunkOverlap   int    `yaml:"chunk_overlap"`   // Default tokens shared between neighbouring chunks
+	MaxResults     int    `yaml:"max_results"`     // Default number of search results
+}
+
+// RerankConfig configures the /v1/rerank endpoint
+type RerankConfig struct {
+	DefaultModel string `yaml:"default_model"` // Model used when a request does not name one
+
+	// Models prompted for a relevance score instead of compared by embedding similarity
+	RerankerModels []string `yaml:"reranker_models"`
+}
+
+// EmbeddingsConfig configures how embedding requests are sent to Ollama
+type EmbeddingsConfig struct {
+	BatchSize int `yaml:"batch_size"` // Maximum inputs per Ollama /api/embed call
+
+	// Models whose embeddings must not be shortened with the dimensions parameter
+	NonTruncatableModels []string `yaml:"non_truncatable_models"`
+
+	Cache    EmbeddingCacheConfig `yaml:"cache"`
+	Chunking ChunkingConfig       `yaml:"chunking"`
+}
+
+// ChunkingConfig configures splitting of inputs longer than an embedding model's context
+type ChunkingConfig struct {
+	Mode           string         `yaml:"mode"`            // Default mode: "none", "mean" or "chunks"
+	ContextLength  int            `yaml:"context_length"`  // Window size in tokens for models not listed below
+	Overlap        int            `yaml:"overlap"`         // Tokens shared between neighbouring windows
+	ContextLengths map[string]int `yaml:"context_lengths"` // Per-model window size in tokens
+}
+
+// EmbeddingCacheConfig configures the embedding result cache
+type EmbeddingCacheConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	MaxEntries    int    `yaml:"max_entries"`    // Size of the in-memory LRU tier
+	DiskPath      string `yaml:"disk_path"`      // Directory for the on-disk tier, disabled when empty
+	DigestRefresh int    `yaml:"digest_refresh"` // Seconds between model digest checks
+}
+
+// ResponsesConfig configures persistence for the Response API
+type ResponsesConfig struct {
+	Store string `yaml:"store"` // "memory", "file" or "none"
+	Path  string `yaml:"path"`  // Directory for the file store
+	TTL   int    `yaml:"ttl"`   // Seconds to keep responses, 0 keeps them forever
 }
 
 // Load reads the configuration from the specified file
@@ -40,12 +170,143 @@ func Load(path string) (*Config, error) {
 	if cfg.OllamaURL == "" {
 		cfg.OllamaURL = "http://localhost:11434"
 	}
+	if len(cfg.OllamaBackends) == 0 {
+		cfg.OllamaBackends = []BackendConfig{{URL: cfg.OllamaURL}}
+	}
+	for i := range cfg.OllamaBackends {
+		b := &cfg.OllamaBackends[i]
+		if b.URL == "" {
+			return nil, fmt.Errorf("ollama backend %d: url is required", i)
+		}
+		if b.Weight <= 0 {
+			b.Weight = 1
+		}
+	}
+	if cfg.LoadBalancing.Strategy == "" {
+		cfg.LoadBalancing.Strategy = "round_robin"
+	}
+	if cfg.LoadBalancing.Strategy != "round_robin" && cfg.LoadBalancing.Strategy != "least_in_flight" {
+		return nil, fmt.Errorf("unknown load balancing strategy: %s", cfg.LoadBalancing.Strategy)
+	}
+	if cfg.LoadBalancing.InventoryRefresh <= 0 {
+		cfg.LoadBalancing.InventoryRefresh = 30
+	}
+	if cfg.LoadBalancing.LoadedRefresh <= 0 {
+		cfg.LoadBalancing.LoadedRefresh = 5
+	}
+	if cfg.LoadBalancing.SessionHeader == "" {
+		cfg.LoadBalancing.SessionHeader = "X-Session-ID"
+	}
+	if cfg.LoadBalancing.SessionTTL <= 0 {
+		cfg.LoadBalancing.SessionTTL = 600
+	}
+	if cfg.HealthCheck.Interval <= 0 {
+		cfg.HealthCheck.Interval = 10
+	}
+	if cfg.HealthCheck.FailureThreshold <= 0 {
+		cfg.HealthCheck.FailureThreshold = 3
+	}
+	if cfg.HealthCheck.OpenDuration <= 0 {
+		cfg.HealthCheck.OpenDuration = 30
+	}
+	if cfg.Retry.MaxAttempts <= 0 {
+		cfg.Retry.MaxAttempts = 3
+	}
+	if cfg.Retry.InitialBackoff <= 0 {
+		cfg.Retry.InitialBackoff = 200
+	}
+	if cfg.Retry.MaxBackoff <= 0 {
+		cfg.Retry.MaxBackoff = 2000
+	}
+	if cfg.Retry.MaxBackoff < cfg.Retry.InitialBackoff {
+		cfg.Retry.MaxBackoff = cfg.Retry.InitialBackoff
+	}
+	for i := range cfg.Providers {
+		p := &cfg.Providers[i]
+		if p.Type == "" {
+			p.Type = "ollama"
+		}
+		if p.Type != "ollama" && p.Type != "openai" {
+			return nil, fmt.Errorf("provider %q: unknown type %q", p.Name, p.Type)
+		}
+		if p.URL == "" {
+			return nil, fmt.Errorf("provider %q: url is required", p.Name)
+		}
+		if p.Name == "" {
+			p.Name = p.URL
+		}
+	}
+	for _, chain := range cfg.ModelFallbacks {
+		if models := splitFallbackChain(chain); len(models) < 2 {
+			return nil, fmt.Errorf("model fallback chain %q: expected at least two models separated by ->", chain)
+		}
+	}
 	if cfg.Timeout == 0 {
 		cfg.Timeout = 300
 	}
 	if cfg.LogLevel == "" {
 		cfg.LogLevel = "info"
 	}
+	if cfg.Responses.Store == "" {
+		cfg.Responses.Store = "memory"
+	}
+	if cfg.Responses.Path == "" {
+		cfg.Responses.Path = "data/responses"
+	}
+	if cfg.Embeddings.BatchSize <= 0 {
+		cfg.Embeddings.BatchSize = 32
+	}
+	if cfg.Embeddings.Cache.MaxEntries <= 0 {
+		cfg.Embeddings.Cache.MaxEntries = 10000
+	}
+	if cfg.Embeddings.Cache.DigestRefresh <= 0 {
+		cfg.Embeddings.Cache.DigestRefresh = 60
+	}
+	if cfg.Embeddings.Chunking.Mode == "" {
+		cfg.Embeddings.Chunking.Mode = "none"
+	}
+	if cfg.Embeddings.Chunking.ContextLength <= 0 {
+		cfg.Embeddings.Chunking.ContextLength = 2048
+	}
+	if cfg.VectorStores.Path == "" {
+		cfg.VectorStores.Path = "data/vector_stores"
+	}
+	if cfg.VectorStores.EmbeddingModel == "" {
+		cfg.VectorStores.EmbeddingModel = "nomic-embed-text"
+	}
+	if cfg.VectorStores.ChunkSize <= 0 {
+		cfg.VectorStores.ChunkSize = 800
+	}
+	if cfg.VectorStores.ChunkOverlap < 0 || cfg.VectorStores.ChunkOverlap >= cfg.VectorStores.ChunkSize {
+		cfg.VectorStores.ChunkOverlap = 0
+	}
+	if cfg.VectorStores.MaxResults <= 0 {
+		cfg.VectorStores.MaxResults = 10
+	}
+	if cfg.Files.Path == "" {
+		cfg.Files.Path = "data/files"
+	}
+	if cfg.Files.MaxSizeMB <= 0 {
+		cfg.Files.MaxSizeMB = 512
+	}
+	if cfg.Batches.Path == "" {
+		cfg.Batches.Path = "data/batches"
+	}
+	if cfg.Batches.Workers <= 0 {
+		cfg.Batches.Workers = 2
+	}
+	if cfg.Batches.BusyWorkers <= 0 {
+		cfg.Batches.BusyWorkers = 1
+	}
+	if cfg.Rerank.DefaultModel == "" {
+		cfg.Rerank.DefaultModel = "nomic-embed-text"
+	}
+	if cfg.Moderation.Model == "" {
+		cfg.Moderation.Model = "llama-guard3"
+	}
+	if len(cfg.Moderation.Categories) == 0 {
+		cfg.Moderation.Categories = defaultModerationCategories()
+	}
 
 	return &cfg, nil
 }
@@ -60,6 +321,148 @@ func (c *Config) GetTimeout() time.Duration {
 	return time.Duration(c.Timeout) * time.Second
 }
 
+// GetResponseTTL returns the stored response lifetime as a Duration
+func (c *Config) GetResponseTTL() time.Duration {
+	return time.Duration(c.Responses.TTL) * time.Second
+}
+
+// GetDigestRefresh returns the model digest refresh interval as a Duration
+func (c *Config) GetDigestRefresh() time.Duration {
+	return time.Duration(c.Embeddings.Cache.DigestRefresh) * time.Second
+}
+
+// GetInventoryRefresh returns the backend model inventory refresh interval as a Duration
+func (c *Config) GetInventoryRefresh() time.Duration {
+	return time.Duration(c.LoadBalancing.InventoryRefresh) * time.Second
+}
+
+// GetLoadedRefresh returns the interval between polls of the backends' loaded models as a Duration
+func (c *Config) GetLoadedRefresh() time.Duration {
+	return time.Duration(c.LoadBalancing.LoadedRefresh) * time.Second
+}
+
+// GetSessionTTL returns how long an idle sticky session stays pinned as a Duration
+func (c *Config) GetSessionTTL() time.Duration {
+	return time.Duration(c.LoadBalancing.SessionTTL) * time.Second
+}
+
+// GetHealthCheckInterval returns the interval between backend probes as a Duration
+func (c *Config) GetHealthCheckInterval() time.Duration {
+	return time.Duration(c.HealthCheck.Interval) * time.Second
+}
+
+// GetCircuitOpenDuration returns how long an open circuit fails fast as a Duration
+func (c *Config) GetCircuitOpenDuration() time.Duration {
+	return time.Duration(c.HealthCheck.OpenDuration) * time.Second
+}
+
+// GetInitialBackoff returns the delay before the first retry as a Duration
+func (c *Config) GetInitialBackoff() time.Duration {
+	return time.Duration(c.Retry.InitialBackoff) * time.Millisecond
+}
+
+// GetMaxBackoff returns the longest delay between retries as a Duration
+func (c *Config) GetMaxBackoff() time.Duration {
+	return time.Duration(c.Retry.MaxBackoff) * time.Millisecond
+}
+
+// GetEmbeddingContextLength returns the chunking window size in tokens for model
+func (c *Config) GetEmbeddingContextLength(model string) int {
+	for m, length := range c.Embeddings.Chunking.ContextLengths {
+		if length > 0 && ollama.NormalizeModelName(m) == ollama.NormalizeModelName(model) {
+			return length
+		}
+	}
+	return c.Embeddings.Chunking.ContextLength
+}
+
+// SupportsDimensions reports whether embeddings from model may be truncated
+// to a requested number of dimensions
+func (c *Config) SupportsDimensions(model string) bool {
+	for _, m := range c.Embeddings.NonTruncatableModels {
+		if ollama.NormalizeModelName(m) == ollama.NormalizeModelName(model) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetFallbackModels returns the models to try in order when model cannot serve
+// a request: those following it in its fallback chain
+func (c *Config) GetFallbackModels(model string) []string {
+	for _, chain := range c.ModelFallbacks {
+		models := splitFallbackChain(chain)
+		for i, m := range models {
+			if ollama.NormalizeModelName(m) == ollama.NormalizeModelName(model) {
+				return models[i+1:]
+			}
+		}
+	}
+	return nil
+}
+
+// splitFallbackChain parses a chain of models separated by "->", returning nil
+// when one of them is empty
+func splitFallbackChain(chain string) []string {
+	models := strings.Split(chain, "->")
+	for i, m := range models {
+		if models[i] = strings.TrimSpace(m); models[i] == "" {
+			return nil
+		}
+	}
+	return models
+}
+
+// GetMaxFileSize returns the largest accepted upload in bytes
+func (c *Config) GetMaxFileSize() int64 {
+	return int64(c.Files.MaxSizeMB) << 20
+}
+
+// IsRerankerModel reports whether model should be prompted for relevance scores
+func (c *Config) IsRerankerModel(model string) bool {
+	for _, m := range c.Rerank.RerankerModels {
+		if ollama.NormalizeModelName(m) == ollama.NormalizeModelName(model) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultModerationCategories maps the Llama Guard 3 hazard categories
+func defaultModerationCategories() map[string][]string {
+	return map[string][]string{
+		"S1":  {"violence", "illicit/violent"}, // Violent crimes
+		"S2":  {"illicit"},                     // Non-violent crimes
+		"S3":  {"sexual"},                      // Sex-related crimes
+		"S4":  {"sexual/minors"},               // Child sexual exploitation
+		"S5":  {"harassment"},                  // Defamation
+		"S9":  {"illicit/violent"},             // Indiscriminate weapons
+		"S10": {"hate"},                        // Hate
+		"S11": {"self-harm"},                   // Suicide and self-harm
+		"S12": {"sexual"},                      // Sexual content
+		"S14": {"illicit"},                     // Code interpreter abuse
+	}
+}
+
+// GetDeploymentModel returns the model served by an Azure deployment,
+// using the deployment name itself when it is not mapped
+func (c *Config) GetDeploymentModel(deployment string) string {
+	if model, ok := c.AzureDeployments[deployment]; ok {
+		return model
+	}
+	return deployment
+}
+
+// IsAdmin reports whether alias may call admin endpoints such as /api/delete
+func (c *Config) IsAdmin(alias string) bool {
+	for _, a := range c.AdminAliases {
+		if a == alias {
+			return true
+		}
+	}
+	return false
+}
+
 // GetAlias returns the alias for a given API key, or empty string if not found
 func (c *Config) GetAlias(key string) string {
 	return c.APIKeys[key]
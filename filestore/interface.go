@@ -0,0 +1,42 @@
+package filestore
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when a file does not exist
+var ErrNotFound = errors.New("not found")
+
+// File describes an uploaded file owned by an API key alias
+type File struct {
+	ID        string    `json:"id"`
+	Alias     string    `json:"alias"`
+	Filename  string    `json:"filename"`
+	Purpose   string    `json:"purpose"`
+	Bytes     int64     `json:"bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Storage defines the interface for keeping uploaded files
+// This allows for different implementations (local disk, object storage, etc.)
+type Storage interface {
+	// Save stores a file's content and metadata, setting file.Bytes
+	Save(file *File, content io.Reader) error
+
+	// Get returns the metadata of the file with the given ID, or ErrNotFound
+	Get(id string) (*File, error)
+
+	// Open returns a reader for the file's content, or ErrNotFound
+	Open(id string) (io.ReadCloser, error)
+
+	// List returns the files owned by alias, oldest first
+	List(alias string) ([]*File, error)
+
+	// Delete removes the file with the given ID, or returns ErrNotFound
+	Delete(id string) error
+}
+
+// Ensure implementations satisfy Storage
+var _ Storage = (*LocalStorage)(nil)
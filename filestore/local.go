@@ -0,0 +1,172 @@
+package filestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"ollama2openai/pkg/fsutil"
+)
+
+// LocalStorage keeps uploaded files in a local directory
+// Layout: <dir>/<id>.json holds the metadata and <dir>/<id>.data the content
+type LocalStorage struct {
+	mu  sync.RWMutex
+	dir string
+}
+
+// NewLocalStorage creates a local file storage rooted at dir
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create file storage directory: %w", err)
+	}
+	return &LocalStorage{dir: dir}, nil
+}
+
+// Save writes the content first so a file is only listed once it is complete
+func (s *LocalStorage) Save(file *File, content io.Reader) error {
+	if !fsutil.ValidID(file.ID) {
+		return fmt.Errorf("invalid file id: %q", file.ID)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, file.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	n, err := io.Copy(tmp, content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	file.Bytes = n
+
+	meta, err := json.Marshal(file)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to marshal file metadata: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Rename(tmp.Name(), s.path(file.ID, ".data")); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to rename file: %w", err)
+	}
+	metaPath := s.path(file.ID, ".json")
+	if err := os.WriteFile(metaPath+".tmp", meta, 0o644); err != nil {
+		return fmt.Errorf("failed to write file metadata: %w", err)
+	}
+	if err := os.Rename(metaPath+".tmp", metaPath); err != nil {
+		return fmt.Errorf("failed to rename file metadata: %w", err)
+	}
+	return nil
+}
+
+// Get reads a file's metadata
+func (s *LocalStorage) Get(id string) (*File, error) {
+	if !fsutil.ValidID(id) {
+		return nil, ErrNotFound
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return readMetadata(s.path(id, ".json"))
+}
+
+// Open opens a file's content for reading
+func (s *LocalStorage) Open(id string) (io.ReadCloser, error) {
+	if !fsutil.ValidID(id) {
+		return nil, ErrNotFound
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, err := os.Open(s.path(id, ".data"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return f, nil
+}
+
+// List returns the files owned by alias, oldest first
+func (s *LocalStorage) List(alias string) ([]*File, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file storage directory: %w", err)
+	}
+
+	var files []*File
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		file, err := readMetadata(filepath.Join(s.dir, e.Name()))
+		if err != nil || file.Alias != alias {
+			continue
+		}
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].CreatedAt.Before(files[j].CreatedAt)
+	})
+	return files, nil
+}
+
+// Delete removes a file's metadata and content
+func (s *LocalStorage) Delete(id string) error {
+	if !fsutil.ValidID(id) {
+		return ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(id, ".json")); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	if err := os.Remove(s.path(id, ".data")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) path(id, ext string) string {
+	return filepath.Join(s.dir, id+ext)
+}
+
+func readMetadata(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read file metadata: %w", err)
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode file metadata: %w", err)
+	}
+	return &file, nil
+}
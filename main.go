@@ -10,11 +10,17 @@ import (
 	"syscall"
 	"time"
 
+	"ollama2openai/batch"
+	"ollama2openai/cache"
 	"ollama2openai/config"
+	"ollama2openai/filestore"
 	"ollama2openai/middleware"
 	"ollama2openai/ollama"
 	"ollama2openai/pkg/logger"
+	"ollama2openai/provider"
 	"ollama2openai/router"
+	"ollama2openai/store"
+	"ollama2openai/vectorstore"
 )
 
 func main() {
@@ -30,27 +36,59 @@ func main() {
 	}
 
 	log.Printf("Starting Ollama2OpenAI Proxy on %s", cfg.GetAddress())
-	log.Printf("Ollama URL: %s", cfg.OllamaURL)
+	for _, b := range cfg.OllamaBackends {
+		log.Printf("Ollama backend: %s (weight %d)", b.URL, b.Weight)
+	}
 
 	// Initialize logger
 	logLevel := logger.ParseLevel(cfg.LogLevel)
 	appLogger := logger.NewStdLogger(logLevel)
 	appLogger.Info("Logger initialized", logger.String("level", cfg.LogLevel))
 
-	// Verify Ollama connection and print models
-	if err := verifyOllamaConnection(cfg.OllamaURL); err != nil {
-		log.Fatalf("Ollama connection failed: %v", err)
+	// Verify Ollama connections and print models, requiring at least one reachable backend
+	reachable := 0
+	for _, b := range cfg.OllamaBackends {
+		if err := verifyOllamaConnection(b.URL); err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		reachable++
+	}
+	if reachable == 0 {
+		log.Fatalf("Ollama connection failed: no backend is reachable")
 	}
 
 	// Create dependencies
-	ollamaClient := ollama.NewClient(cfg.OllamaURL, cfg.GetTimeout())
-	usageTracker := middleware.NewUsageStats()
+	backends := provider.NewPool(cfg, appLogger)
+	upstreams := provider.NewRegistry(cfg, backends, appLogger)
+	usageTracker := middleware.GetGlobalStats() // Shared with the /usage handler
+	responseStore, err := newResponseStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create response store: %v", err)
+	}
+	embedCache, err := newEmbeddingCache(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create embedding cache: %v", err)
+	}
+	vectorIndex, err := vectorstore.NewFlatIndex(cfg.VectorStores.Path)
+	if err != nil {
+		log.Fatalf("Failed to open vector stores: %v", err)
+	}
+	fileStorage, err := filestore.NewLocalStorage(cfg.Files.Path)
+	if err != nil {
+		log.Fatalf("Failed to create file storage: %v", err)
+	}
+
+	batchStore, err := batch.NewFileStore(cfg.Batches.Path)
+	if err != nil {
+		log.Fatalf("Failed to create batch store: %v", err)
+	}
 
 	// Create a custom ServeMux to handle routes
 	mux := http.NewServeMux()
 
 	// Setup routes with dependency injection
-	rt := router.NewRouter(cfg, ollamaClient, usageTracker, appLogger)
+	rt := router.NewRouter(cfg, upstreams, backends, usageTracker, appLogger, responseStore, embedCache, vectorIndex, fileStorage, batchStore)
 	rt.SetupRoutes(mux)
 
 	// Create server
@@ -104,9 +142,9 @@ func verifyOllamaConnection(ollamaURL string) error {
 	}
 
 	if len(resp.Models) == 0 {
-		log.Printf("Warning: No models found in Ollama")
+		log.Printf("Warning: No models found in Ollama at %s", ollamaURL)
 	} else {
-		log.Printf("Ollama is connected. Available models:")
+		log.Printf("Ollama at %s is connected. Available models:", ollamaURL)
 		for _, m := range resp.Models {
 			log.Printf("  - %s", m.Name)
 		}
@@ -114,3 +152,25 @@ func verifyOllamaConnection(ollamaURL string) error {
 
 	return nil
 }
+
+// newResponseStore creates the Response API store selected in the configuration
+func newResponseStore(cfg *config.Config) (store.ResponseStore, error) {
+	switch cfg.Responses.Store {
+	case "memory":
+		return store.NewMemoryResponseStore(cfg.GetResponseTTL()), nil
+	case "file":
+		return store.NewFileResponseStore(cfg.Responses.Path, cfg.GetResponseTTL())
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown response store: %s", cfg.Responses.Store)
+	}
+}
+
+// newEmbeddingCache creates the embedding cache, or returns nil when it is disabled
+func newEmbeddingCache(cfg *config.Config) (cache.EmbeddingCache, error) {
+	if !cfg.Embeddings.Cache.Enabled {
+		return nil, nil
+	}
+	return cache.NewTieredCache(cfg.Embeddings.Cache.MaxEntries, cfg.Embeddings.Cache.DiskPath)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"ollama2openai/config"
 )
@@ -22,8 +23,15 @@ func WithAuth(handler http.Handler, cfg *config.Config) http.Handler {
 			return
 		}
 
-		// Get Authorization header
+		queryKey := takeQueryAPIKey(r)
+
+		// Get Authorization header, falling back to the key headers used by other SDKs
 		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			if key := alternateAPIKey(r, queryKey); key != "" {
+				authHeader = "Bearer " + key
+			}
+		}
 		if authHeader == "" {
 			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
 			writeError(w, http.StatusUnauthorized, "Missing API key")
@@ -53,6 +61,44 @@ func WithAuth(handler http.Handler, cfg *config.Config) http.Handler {
 	})
 }
 
+// takeQueryAPIKey removes the key query parameter so it is never relayed
+// upstream, and returns it for the Gemini routes that accept it
+func takeQueryAPIKey(r *http.Request) string {
+	query := r.URL.Query()
+	if !query.Has("key") {
+		return ""
+	}
+
+	key := query.Get("key")
+	query.Del("key")
+	r.URL.RawQuery = query.Encode()
+	if !strings.HasPrefix(r.URL.Path, "/v1beta/") {
+		return ""
+	}
+	return key
+}
+
+// alternateAPIKey returns a key sent the Azure way (api-key), the Anthropic
+// way (x-api-key) or the Gemini way (x-goog-api-key or the key query parameter)
+func alternateAPIKey(r *http.Request, queryKey string) string {
+	if key := r.Header.Get("api-key"); key != "" {
+		return key
+	}
+	if key := r.Header.Get("x-api-key"); key != "" {
+		return key
+	}
+	if key := r.Header.Get("x-goog-api-key"); key != "" {
+		return key
+	}
+	return queryKey
+}
+
+// WithAlias returns a context carrying alias, for requests made on behalf of
+// an API key without going through WithAuth
+func WithAlias(ctx context.Context, alias string) context.Context {
+	return context.WithValue(ctx, aliasContextKey, alias)
+}
+
 // GetAliasFromContext retrieves the alias from context
 func GetAliasFromContext(ctx context.Context) string {
 	if alias, ok := ctx.Value(aliasContextKey).(string); ok {
@@ -19,6 +19,14 @@ type UsageRecord struct {
 	EmbeddingTokens    int64
 	TotalRequests      int64
 	EmbeddingRequests  int64
+	Models             map[string]*ModelUsage // Completion usage by the model that served it
+}
+
+// ModelUsage is the completion usage of an alias for one model
+type ModelUsage struct {
+	PromptTokens     int64
+	CompletionTokens int64
+	Requests         int64
 }
 
 // Global usage stats instance
@@ -32,8 +40,8 @@ func NewUsageStats() *UsageStats {
 	}
 }
 
-// RecordCompletion records tokens for a completion request
-func (s *UsageStats) RecordCompletion(alias string, promptTokens, completionTokens int64) {
+// RecordCompletion records tokens for a completion request served by model
+func (s *UsageStats) RecordCompletion(alias, model string, promptTokens, completionTokens int64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -43,6 +51,16 @@ func (s *UsageStats) RecordCompletion(alias string, promptTokens, completionToke
 	s.usage[alias].PromptTokens += promptTokens
 	s.usage[alias].CompletionTokens += completionTokens
 	s.usage[alias].TotalRequests++
+
+	if s.usage[alias].Models == nil {
+		s.usage[alias].Models = make(map[string]*ModelUsage)
+	}
+	if _, ok := s.usage[alias].Models[model]; !ok {
+		s.usage[alias].Models[model] = &ModelUsage{}
+	}
+	s.usage[alias].Models[model].PromptTokens += promptTokens
+	s.usage[alias].Models[model].CompletionTokens += completionTokens
+	s.usage[alias].Models[model].Requests++
 }
 
 // RecordEmbedding records tokens for an embedding request
@@ -64,12 +82,18 @@ func (s *UsageStats) GetStats() map[string]*UsageRecord {
 
 	result := make(map[string]*UsageRecord)
 	for k, v := range s.usage {
+		models := make(map[string]*ModelUsage, len(v.Models))
+		for model, u := range v.Models {
+			copied := *u
+			models[model] = &copied
+		}
 		result[k] = &UsageRecord{
 			PromptTokens:      v.PromptTokens,
 			CompletionTokens:  v.CompletionTokens,
 			EmbeddingTokens:   v.EmbeddingTokens,
 			TotalRequests:     v.TotalRequests,
 			EmbeddingRequests: v.EmbeddingRequests,
+			Models:            models,
 		}
 	}
 	return result
@@ -50,7 +50,7 @@ func (c *Client) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, err
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("ollama returned error: %d - %s", resp.StatusCode, string(respBody))
+		return nil, NewStatusError(resp.StatusCode, respBody)
 	}
 
 	var chatResp ChatResponse
@@ -61,16 +61,38 @@ func (c *Client) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, err
 	return &chatResp, nil
 }
 
-// ChatStream is a channel that receives streaming chat responses
-type ChatStream struct {
-	responses chan ChatResponse
+// StreamResponse is a response read from a Stream
+type StreamResponse interface {
+	ChatResponse | GenerateResponse
+}
+
+// Stream is a channel that receives streaming responses
+type Stream[T StreamResponse] struct {
+	responses chan T
 	err       error
 	done      chan struct{}
+	model     string // Model serving the stream, when recorded with SetModel
 }
 
-// Chat sends a streaming chat request to Ollama and returns a stream reader
+// ChatStream is a stream of chat responses
+type ChatStream = Stream[ChatResponse]
+
+// GenerateStream is a stream of generate responses
+type GenerateStream = Stream[GenerateResponse]
+
+// ChatStream sends a streaming chat request to Ollama and returns a stream reader
 func (c *Client) ChatStream(ctx context.Context, req *ChatRequest) (*ChatStream, error) {
-	url := fmt.Sprintf("%s/api/chat", c.baseURL)
+	return openStream[ChatResponse](ctx, c, "/api/chat", req)
+}
+
+// GenerateStream sends a streaming generate request to Ollama and returns a stream reader
+func (c *Client) GenerateStream(ctx context.Context, req *GenerateRequest) (*GenerateStream, error) {
+	return openStream[GenerateResponse](ctx, c, "/api/generate", req)
+}
+
+// openStream posts a streaming request to path and decodes the NDJSON responses
+func openStream[T StreamResponse](ctx context.Context, c *Client, path string, req interface{}) (*Stream[T], error) {
+	url := fmt.Sprintf("%s%s", c.baseURL, path)
 
 	body, err := json.Marshal(req)
 	if err != nil {
@@ -88,19 +110,53 @@ func (c *Client) ChatStream(ctx context.Context, req *ChatRequest) (*ChatStream,
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
-	stream := &ChatStream{
-		responses: make(chan ChatResponse, 10),
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, NewStatusError(resp.StatusCode, respBody)
+	}
+
+	// Failures after the response has started, such as a model that cannot be
+	// loaded, arrive as an {"error": "..."} line
+	decoder := json.NewDecoder(resp.Body)
+	return NewStream(ctx, resp.Body, func() (T, error) {
+		var resp T
+		var line json.RawMessage
+		if err := decoder.Decode(&line); err != nil {
+			return resp, err
+		}
+
+		var failure struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(line, &failure) == nil && failure.Error != "" {
+			return resp, fmt.Errorf("ollama stream error: %s", failure.Error)
+		}
+		return resp, json.Unmarshal(line, &resp)
+	}), nil
+}
+
+// NewChatStream returns a stream of the chat responses produced by next; see NewStream
+func NewChatStream(ctx context.Context, body io.Closer, next func() (ChatResponse, error)) *ChatStream {
+	return NewStream(ctx, body, next)
+}
+
+// NewStream returns a stream of the responses produced by next, which
+// returns io.EOF at the end; body is closed once the stream ends
+// It lets upstreams other than Ollama stream through the same interface
+func NewStream[T StreamResponse](ctx context.Context, body io.Closer, next func() (T, error)) *Stream[T] {
+	stream := &Stream[T]{
+		responses: make(chan T, 10),
 		done:      make(chan struct{}),
 	}
 
 	go func() {
 		defer close(stream.responses)
-		defer resp.Body.Close()
+		defer body.Close()
 
-		decoder := json.NewDecoder(resp.Body)
 		for {
-			var chatResp ChatResponse
-			if err := decoder.Decode(&chatResp); err != nil {
+			resp, err := next()
+			if err != nil {
 				if err == io.EOF || ctx.Err() != nil {
 					return
 				}
@@ -108,38 +164,64 @@ func (c *Client) ChatStream(ctx context.Context, req *ChatRequest) (*ChatStream,
 				return
 			}
 			select {
-			case stream.responses <- chatResp:
+			case stream.responses <- resp:
 			case <-ctx.Done():
 				return
 			case <-stream.done:
 				return
 			}
-			if chatResp.Done {
+			if isLast(resp) {
 				return
 			}
 		}
 	}()
 
-	return stream, nil
+	return stream
+}
+
+// isLast reports whether resp is the final response of its stream
+func isLast[T StreamResponse](resp T) bool {
+	switch r := any(resp).(type) {
+	case ChatResponse:
+		return r.Done
+	case GenerateResponse:
+		return r.Done
+	}
+	return false
 }
 
 // ReadResponse reads a single response from the stream
-func (s *ChatStream) ReadResponse() (ChatResponse, error) {
+// It returns io.EOF once the stream has ended without an error
+func (s *Stream[T]) ReadResponse() (T, error) {
 	resp, ok := <-s.responses
 	if !ok {
-		return ChatResponse{}, s.err
+		if s.err != nil {
+			return resp, s.err
+		}
+		return resp, io.EOF
 	}
 	return resp, nil
 }
 
 // Close closes the stream
-func (s *ChatStream) Close() {
+func (s *Stream[T]) Close() {
 	close(s.done)
 }
 
-// Embedding sends an embedding request to Ollama
+// SetModel records the model serving the stream, which may differ from the
+// requested one when a fallback model served it
+func (s *Stream[T]) SetModel(model string) {
+	s.model = model
+}
+
+// Model returns the model recorded with SetModel, or "" if none was
+func (s *Stream[T]) Model() string {
+	return s.model
+}
+
+// Embedding sends a batched embedding request to Ollama's /api/embed endpoint
 func (c *Client) Embedding(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
-	url := fmt.Sprintf("%s/api/embeddings", c.baseURL)
+	url := fmt.Sprintf("%s/api/embed", c.baseURL)
 
 	body, err := json.Marshal(req)
 	if err != nil {
@@ -160,7 +242,7 @@ func (c *Client) Embedding(ctx context.Context, req *EmbeddingRequest) (*Embeddi
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("ollama returned error: %d - %s", resp.StatusCode, string(respBody))
+		return nil, NewStatusError(resp.StatusCode, respBody)
 	}
 
 	var embedResp EmbeddingResponse
@@ -188,7 +270,7 @@ func (c *Client) Tags(ctx context.Context) (*TagsResponse, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("ollama returned error: %d - %s", resp.StatusCode, string(respBody))
+		return nil, NewStatusError(resp.StatusCode, respBody)
 	}
 
 	var tagsResp TagsResponse
@@ -199,6 +281,64 @@ func (c *Client) Tags(ctx context.Context) (*TagsResponse, error) {
 	return &tagsResp, nil
 }
 
+// Version returns the version of the Ollama server
+func (c *Client) Version(ctx context.Context) (string, error) {
+	url := fmt.Sprintf("%s/api/version", c.baseURL)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", NewStatusError(resp.StatusCode, respBody)
+	}
+
+	var versionResp struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&versionResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return versionResp.Version, nil
+}
+
+// Ps lists the models currently loaded in memory
+func (c *Client) Ps(ctx context.Context) (*PsResponse, error) {
+	url := fmt.Sprintf("%s/api/ps", c.baseURL)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, NewStatusError(resp.StatusCode, respBody)
+	}
+
+	var psResp PsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&psResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &psResp, nil
+}
+
 // Generate sends a generate request to Ollama (non-streaming)
 func (c *Client) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
 	url := fmt.Sprintf("%s/api/generate", c.baseURL)
@@ -222,7 +362,7 @@ func (c *Client) Generate(ctx context.Context, req *GenerateRequest) (*GenerateR
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("ollama returned error: %d - %s", resp.StatusCode, string(respBody))
+		return nil, NewStatusError(resp.StatusCode, respBody)
 	}
 
 	var genResp GenerateResponse
@@ -232,3 +372,24 @@ func (c *Client) Generate(ctx context.Context, req *GenerateRequest) (*GenerateR
 
 	return &genResp, nil
 }
+
+// Forward sends a request to an arbitrary Ollama API path and returns the raw
+// response, which the caller must close; non-200 responses are not treated as errors
+func (c *Client) Forward(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	url := fmt.Sprintf("%s%s", c.baseURL, path)
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,48 @@
+package ollama
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// StatusError is returned when Ollama answers a request with a non-200 status
+type StatusError struct {
+	StatusCode int
+	Message    string // The "error" field of the response body, when it has one
+	Body       string // Raw response body
+}
+
+// NewStatusError creates a StatusError, parsing Ollama's {"error": "..."} body
+func NewStatusError(statusCode int, body []byte) *StatusError {
+	var parsed struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(body, &parsed)
+
+	return &StatusError{
+		StatusCode: statusCode,
+		Message:    parsed.Error,
+		Body:       string(body),
+	}
+}
+
+// Error implements the error interface
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("ollama returned error: %d - %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("ollama returned error: %d - %s", e.StatusCode, e.Body)
+}
+
+// UpstreamStatus returns the HTTP status Ollama answered with
+func (e *StatusError) UpstreamStatus() int {
+	return e.StatusCode
+}
+
+// UpstreamMessage returns Ollama's error message, or the raw body when it could not be parsed
+func (e *StatusError) UpstreamMessage() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return e.Body
+}
@@ -1,6 +1,10 @@
 package ollama
 
-import "context"
+import (
+	"context"
+	"io"
+	"net/http"
+)
 
 // ClientInterface defines the interface for Ollama API operations
 // This allows for easy mocking and testing
@@ -11,7 +15,7 @@ type ClientInterface interface {
 	// ChatStream sends a streaming chat completion request
 	ChatStream(ctx context.Context, req *ChatRequest) (*ChatStream, error)
 
-	// Embedding sends an embedding request
+	// Embedding sends an embedding request for one or more inputs
 	Embedding(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error)
 
 	// Tags lists all available models
@@ -19,6 +23,12 @@ type ClientInterface interface {
 
 	// Generate sends a generate request (alternative to chat)
 	Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error)
+
+	// GenerateStream sends a streaming generate request
+	GenerateStream(ctx context.Context, req *GenerateRequest) (*GenerateStream, error)
+
+	// Forward relays a native API request, such as /api/chat, and returns the raw response
+	Forward(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error)
 }
 
 // Ensure Client implements ClientInterface
@@ -0,0 +1,12 @@
+package ollama
+
+import "strings"
+
+// NormalizeModelName adds the default ":latest" tag to model names without one,
+// so "llama3" and "llama3:latest" compare equal
+func NormalizeModelName(model string) string {
+	if model == "" || strings.Contains(model, ":") {
+		return model
+	}
+	return model + ":latest"
+}
@@ -4,10 +4,11 @@ package ollama
 type ChatRequest struct {
 	Model    string       `json:"model"`
 	Messages []ChatMessage `json:"messages"`
-	Stream   bool         `json:"stream,omitempty"`
+	Stream   bool         `json:"stream"`
 	Format   string       `json:"format,omitempty"`
 	Options  map[string]interface{} `json:"options,omitempty"`
 	KeepAlive interface{}  `json:"keep_alive,omitempty"`
+	Tools    []Tool       `json:"tools,omitempty"`
 }
 
 // Ollama Chat Message
@@ -15,6 +16,33 @@ type ChatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
 	Images  []string `json:"images,omitempty"` // Base64 encoded images
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	ToolName string `json:"tool_name,omitempty"` // Name of the tool a "tool" message answers
+}
+
+// Ollama Tool - a function the model may call
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function
+type ToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ToolCall is a function call requested by the model
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction holds the called function name and its arguments
+// Unlike OpenAI, Ollama passes arguments as a JSON object rather than a string
+type ToolCallFunction struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
 }
 
 // Ollama Chat Response
@@ -23,6 +51,7 @@ type ChatResponse struct {
 	CreatedAt          string   `json:"created_at"`
 	Message            ChatMessage `json:"message"`
 	Done               bool     `json:"done"`
+	DoneReason         string   `json:"done_reason,omitempty"` // "stop", "length", ...
 	TotalDuration      int64    `json:"total_duration,omitempty"`
 	LoadDuration       int64    `json:"load_duration,omitempty"`
 	PromptEvalCount    int      `json:"prompt_eval_count,omitempty"`
@@ -31,20 +60,22 @@ type ChatResponse struct {
 	EvalDuration       int64    `json:"eval_duration,omitempty"`
 }
 
-// Ollama Embedding Request
+// Ollama Embedding Request (/api/embed), embedding several inputs in one call
 type EmbeddingRequest struct {
 	Model  string   `json:"model"`
-	Input  string   `json:"input"`
+	Input  []string `json:"input"`
+	Truncate *bool  `json:"truncate,omitempty"`
 	Options map[string]interface{} `json:"options,omitempty"`
 	KeepAlive interface{} `json:"keep_alive,omitempty"`
 }
 
-// Ollama Embedding Response
+// Ollama Embedding Response, with one embedding per input in request order
 type EmbeddingResponse struct {
 	Model     string     `json:"model"`
 	Embeddings [][]float64 `json:"embeddings"`
 	TotalDuration int64  `json:"total_duration,omitempty"`
 	LoadDuration  int64  `json:"load_duration,omitempty"`
+	PromptEvalCount int  `json:"prompt_eval_count,omitempty"`
 }
 
 // Ollama Tags Response (for listing models)
@@ -70,11 +101,26 @@ type ModelDetails struct {
 	QuantizationLevel string `json:"quantization_level"`
 }
 
+// Ollama Ps Response (for listing models loaded in memory)
+type PsResponse struct {
+	Models []RunningModel `json:"models"`
+}
+
+type RunningModel struct {
+	Name      string        `json:"name"`
+	Model     string        `json:"model"`
+	Size      int64         `json:"size"`
+	Digest    string        `json:"digest"`
+	Details   *ModelDetails `json:"details,omitempty"`
+	ExpiresAt string        `json:"expires_at"`
+	SizeVRAM  int64         `json:"size_vram"`
+}
+
 // Ollama Generate Request (alternative to chat)
 type GenerateRequest struct {
 	Model    string   `json:"model"`
 	Prompt   string   `json:"prompt"`
-	Stream   bool     `json:"stream,omitempty"`
+	Stream   bool     `json:"stream"`
 	Format   string   `json:"format,omitempty"`
 	Options  map[string]interface{} `json:"options,omitempty"`
 	KeepAlive interface{} `json:"keep_alive,omitempty"`
@@ -86,6 +132,7 @@ type GenerateResponse struct {
 	CreatedAt          string   `json:"created_at"`
 	Response           string   `json:"response"`
 	Done               bool     `json:"done"`
+	DoneReason         string   `json:"done_reason,omitempty"` // "stop", "length", ...
 	TotalDuration      int64    `json:"total_duration,omitempty"`
 	LoadDuration       int64    `json:"load_duration,omitempty"`
 	PromptEvalCount    int      `json:"prompt_eval_count,omitempty"`
@@ -1,5 +1,7 @@
 package openai
 
+import "encoding/json"
+
 // Chat Completion Request - matches OpenAI API spec
 type ChatCompletionRequest struct {
 	Model            string                  `json:"model"`
@@ -25,6 +27,7 @@ type ChatMessage struct {
 	Content interface{} `json:"content"` // Can be string or []ContentPart
 	Name    string      `json:"name,omitempty"`
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string   `json:"tool_call_id,omitempty"` // Set on "tool" role messages
 }
 
 // ContentPart represents a part of message content (for vision/multimodal)
@@ -55,6 +58,7 @@ type ToolFunc struct {
 
 // ToolCall represents a tool call in a message
 type ToolCall struct {
+	Index    *int             `json:"index,omitempty"` // Only set in streaming deltas
 	ID       string           `json:"id"`
 	Type     string           `json:"type"`
 	Function *ToolCallFunction `json:"function,omitempty"`
@@ -97,13 +101,44 @@ type Usage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
+// Completion Request (legacy completions API)
+type CompletionRequest struct {
+	Model       string      `json:"model"`
+	Prompt      interface{} `json:"prompt"` // String, []string, token array or array of token arrays
+	MaxTokens   *int        `json:"max_tokens,omitempty"`
+	Temperature *float64    `json:"temperature,omitempty"`
+	TopP        *float64    `json:"top_p,omitempty"`
+	Stream      bool        `json:"stream,omitempty"`
+	Stop        interface{} `json:"stop,omitempty"`
+	User        string      `json:"user,omitempty"`
+}
+
+// Completion Response
+type CompletionResponse struct {
+	ID      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model"`
+	Choices []CompletionChoice `json:"choices"`
+	Usage   *Usage             `json:"usage,omitempty"`
+}
+
+// CompletionChoice represents a choice in a completion response
+type CompletionChoice struct {
+	Text         string      `json:"text"`
+	Index        int         `json:"index"`
+	Logprobs     interface{} `json:"logprobs"`
+	FinishReason string      `json:"finish_reason"`
+}
+
 // Embedding Request
 type EmbeddingRequest struct {
 	Model    string   `json:"model"`
-	Input    interface{} `json:"input"` // Can be string or []string
+	Input    interface{} `json:"input"` // String, []string, token array or array of token arrays
 	User     string   `json:"user,omitempty"`
 	EncodingFormat *string `json:"encoding_format,omitempty"`
 	Dimensions *int     `json:"dimensions,omitempty"`
+	Chunking   string   `json:"chunking,omitempty"` // Extension: "none", "mean" or "chunks"
 }
 
 // Embedding Response
@@ -117,8 +152,16 @@ type EmbeddingResponse struct {
 // EmbeddingData represents a single embedding
 type EmbeddingData struct {
 	Object    string    `json:"object"`
-	Embedding []float64 `json:"embedding"`
+	Embedding interface{} `json:"embedding"` // []float64, or a base64 string when encoding_format is "base64"
 	Index     int       `json:"index"`
+	Chunks    []EmbeddingChunk `json:"chunks,omitempty"` // Extension: per-window embeddings with chunking "chunks"
+}
+
+// EmbeddingChunk is the embedding of one window of an over-length input
+type EmbeddingChunk struct {
+	Embedding interface{} `json:"embedding"`
+	Start     int         `json:"start"` // Byte offset of the window in the input
+	End       int         `json:"end"`
 }
 
 // EmbeddingUsage represents usage for embeddings
@@ -127,6 +170,56 @@ type EmbeddingUsage struct {
 	TotalTokens  int `json:"total_tokens"`
 }
 
+// Rerank Request (Cohere/Jina style)
+type RerankRequest struct {
+	Model           string        `json:"model"`
+	Query           string        `json:"query"`
+	Documents       []interface{} `json:"documents"` // Strings or objects with a "text" field
+	TopN            *int          `json:"top_n,omitempty"`
+	ReturnDocuments *bool         `json:"return_documents,omitempty"`
+}
+
+// Rerank Response, with results sorted by descending relevance
+type RerankResponse struct {
+	ID      string         `json:"id"`
+	Model   string         `json:"model"`
+	Results []RerankResult `json:"results"`
+	Usage   EmbeddingUsage `json:"usage"`
+}
+
+// RerankResult scores one document, identified by its index in the request
+type RerankResult struct {
+	Index          int             `json:"index"`
+	RelevanceScore float64         `json:"relevance_score"`
+	Document       *RerankDocument `json:"document,omitempty"`
+}
+
+// RerankDocument holds the text of a reranked document
+type RerankDocument struct {
+	Text string `json:"text"`
+}
+
+// ModerationRequest classifies text, a list of texts, or multimodal content parts
+type ModerationRequest struct {
+	Model string      `json:"model,omitempty"`
+	Input interface{} `json:"input"`
+}
+
+// ModerationResponse holds one result per input
+type ModerationResponse struct {
+	ID      string             `json:"id"`
+	Model   string             `json:"model"`
+	Results []ModerationResult `json:"results"`
+}
+
+// ModerationResult is the verdict for one input
+type ModerationResult struct {
+	Flagged                   bool                `json:"flagged"`
+	Categories                map[string]bool     `json:"categories"`
+	CategoryScores            map[string]float64  `json:"category_scores"`
+	CategoryAppliedInputTypes map[string][]string `json:"category_applied_input_types,omitempty"`
+}
+
 // Model represents a model in the OpenAI API
 type Model struct {
 	ID          string `json:"id"`
@@ -172,11 +265,29 @@ type StreamChoice struct {
 type ResponseRequest struct {
 	Model       string         `json:"model"`
 	Input       interface{}    `json:"input,omitempty"`
-	Tools       []Tool         `json:"tools,omitempty"`
+	Instructions string        `json:"instructions,omitempty"`
+	PreviousResponseID string  `json:"previous_response_id,omitempty"`
+	Store       *bool          `json:"store,omitempty"`
+	Tools       []ResponseTool `json:"tools,omitempty"`
 	ToolChoice  interface{}    `json:"tool_choice,omitempty"`
 	MaxOutputTokens *int       `json:"max_output_tokens,omitempty"`
 	Temperature *float64       `json:"temperature,omitempty"`
 	Stream      bool           `json:"stream,omitempty"`
+	Background  bool           `json:"background,omitempty"`
+	Include     []string       `json:"include,omitempty"` // e.g. "file_search_call.results"
+}
+
+// ResponseTool represents a tool in a Response API request
+// Function tools are flat here, unlike the nested Tool used by chat completions
+type ResponseTool struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Strict      *bool                  `json:"strict,omitempty"`
+	Function    *ToolFunc              `json:"function,omitempty"` // Chat completions style, accepted for convenience
+	VectorStoreIDs []string            `json:"vector_store_ids,omitempty"` // file_search only
+	MaxNumResults  *int                `json:"max_num_results,omitempty"`  // file_search only
 }
 
 type ResponseResponse struct {
@@ -184,12 +295,289 @@ type ResponseResponse struct {
 	Object      string          `json:"object"`
 	Created     int64           `json:"created"`
 	Model       string          `json:"model"`
+	Status      string          `json:"status"` // queued, in_progress, completed, failed or cancelled
+	Background  bool            `json:"background,omitempty"`
+	PreviousResponseID string   `json:"previous_response_id,omitempty"`
 	Output      []OutputItem    `json:"output"`
 	Usage       Usage           `json:"usage"`
+	Error       *ErrorDetail    `json:"error,omitempty"`
 }
 
 type OutputItem struct {
+	ID      string        `json:"id,omitempty"`
 	Type    string        `json:"type"`
+	Status  string        `json:"status,omitempty"`
 	Content []ContentPart `json:"content,omitempty"`
 	Role    string        `json:"role,omitempty"`
+	CallID  string        `json:"call_id,omitempty"`   // function_call only
+	Name    string        `json:"name,omitempty"`      // function_call only
+	Arguments string      `json:"arguments,omitempty"` // function_call only, JSON encoded
+	Queries   []string    `json:"queries,omitempty"`   // file_search_call only
+	Results   []FileSearchResult `json:"results,omitempty"` // file_search_call only, when requested via include
+}
+
+// FileSearchResult is a chunk retrieved by the file_search tool
+type FileSearchResult struct {
+	FileID     string                 `json:"file_id"`
+	Filename   string                 `json:"filename"`
+	Score      float64                `json:"score"`
+	Text       string                 `json:"text"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// InputItem represents an input item recorded for a stored response
+type InputItem struct {
+	ID      string        `json:"id"`
+	Type    string        `json:"type"`
+	Role    string        `json:"role,omitempty"`
+	Content []ContentPart `json:"content,omitempty"`
+	CallID  string        `json:"call_id,omitempty"`
+	Name    string        `json:"name,omitempty"`
+	Arguments string      `json:"arguments,omitempty"`
+	Output  string        `json:"output,omitempty"` // function_call_output only
+}
+
+// InputItemList is the response for listing a stored response's input items
+type InputItemList struct {
+	Object  string      `json:"object"`
+	Data    []InputItem `json:"data"`
+	FirstID string      `json:"first_id,omitempty"`
+	LastID  string      `json:"last_id,omitempty"`
+	HasMore bool        `json:"has_more"`
+}
+
+// ResponseStreamEvent is a server-sent event emitted while a response is generated
+type ResponseStreamEvent struct {
+	Type           string            `json:"type"`
+	SequenceNumber int               `json:"sequence_number"`
+	Response       *ResponseResponse `json:"response,omitempty"`
+	ItemID         string            `json:"item_id,omitempty"`
+	OutputIndex    *int              `json:"output_index,omitempty"`
+	ContentIndex   *int              `json:"content_index,omitempty"`
+	Delta          string            `json:"delta,omitempty"`
+	Code           string            `json:"code,omitempty"`    // error
+	Message        string            `json:"message,omitempty"` // error
+}
+
+// DeletedObject is returned when a stored response, vector store or file is deleted
+type DeletedObject struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
+
+// ChunkingStrategy controls how documents added to a vector store are split
+type ChunkingStrategy struct {
+	Type   string          `json:"type"` // "auto" or "static"
+	Static *StaticChunking `json:"static,omitempty"`
+}
+
+// StaticChunking sets a fixed chunk size and overlap in tokens
+type StaticChunking struct {
+	MaxChunkSizeTokens int `json:"max_chunk_size_tokens"`
+	ChunkOverlapTokens int `json:"chunk_overlap_tokens"`
+}
+
+// VectorStoreRequest creates a vector store or modifies its name and metadata
+type VectorStoreRequest struct {
+	Name             *string           `json:"name,omitempty"`
+	Model            string            `json:"model,omitempty"` // Embedding model, fixed when the store is created
+	Metadata         map[string]string `json:"metadata,omitempty"`
+	FileIDs          []string          `json:"file_ids,omitempty"` // Uploaded files to add on creation
+	ChunkingStrategy *ChunkingStrategy `json:"chunking_strategy,omitempty"`
+}
+
+// VectorStore represents a vector store in the OpenAI API
+type VectorStore struct {
+	ID         string                `json:"id"`
+	Object     string                `json:"object"`
+	CreatedAt  int64                 `json:"created_at"`
+	Name       string                `json:"name"`
+	UsageBytes int64                 `json:"usage_bytes"`
+	FileCounts VectorStoreFileCounts `json:"file_counts"`
+	Status     string                `json:"status"`
+	Metadata   map[string]string     `json:"metadata"`
+}
+
+// VectorStoreFileCounts counts a vector store's files by status
+type VectorStoreFileCounts struct {
+	InProgress int `json:"in_progress"`
+	Completed  int `json:"completed"`
+	Failed     int `json:"failed"`
+	Cancelled  int `json:"cancelled"`
+	Total      int `json:"total"`
+}
+
+// VectorStoreList is a page of vector stores
+type VectorStoreList struct {
+	Object  string        `json:"object"`
+	Data    []VectorStore `json:"data"`
+	FirstID string        `json:"first_id,omitempty"`
+	LastID  string        `json:"last_id,omitempty"`
+	HasMore bool          `json:"has_more"`
+}
+
+// VectorStoreFileRequest adds a document to a vector store
+// Text is accepted in place of file_id to index a document without uploading it first
+type VectorStoreFileRequest struct {
+	FileID           string                 `json:"file_id,omitempty"`
+	Text             string                 `json:"text,omitempty"`
+	Filename         string                 `json:"filename,omitempty"`
+	Attributes       map[string]interface{} `json:"attributes,omitempty"`
+	ChunkingStrategy *ChunkingStrategy      `json:"chunking_strategy,omitempty"`
+}
+
+// VectorStoreFile represents a document in a vector store
+type VectorStoreFile struct {
+	ID            string                 `json:"id"`
+	Object        string                 `json:"object"`
+	CreatedAt     int64                  `json:"created_at"`
+	VectorStoreID string                 `json:"vector_store_id"`
+	Status        string                 `json:"status"`
+	UsageBytes    int64                  `json:"usage_bytes"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	LastError     *ErrorDetail           `json:"last_error"`
+}
+
+// VectorStoreFileList is a page of vector store files
+type VectorStoreFileList struct {
+	Object  string            `json:"object"`
+	Data    []VectorStoreFile `json:"data"`
+	FirstID string            `json:"first_id,omitempty"`
+	LastID  string            `json:"last_id,omitempty"`
+	HasMore bool              `json:"has_more"`
+}
+
+// VectorStoreSearchRequest searches a vector store
+type VectorStoreSearchRequest struct {
+	Query         interface{} `json:"query"` // A string or an array of strings
+	MaxNumResults *int        `json:"max_num_results,omitempty"`
+}
+
+// VectorStoreSearchResult is a chunk matching a vector store search
+type VectorStoreSearchResult struct {
+	FileID     string                 `json:"file_id"`
+	Filename   string                 `json:"filename"`
+	Score      float64                `json:"score"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Content    []ContentPart          `json:"content"`
+}
+
+// VectorStoreSearchPage is the result of a vector store search
+type VectorStoreSearchPage struct {
+	Object      string                    `json:"object"`
+	SearchQuery []string                  `json:"search_query"`
+	Data        []VectorStoreSearchResult `json:"data"`
+	HasMore     bool                      `json:"has_more"`
+	NextPage    *string                   `json:"next_page"`
+}
+
+// FileObject represents an uploaded file in the OpenAI API
+type FileObject struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status"`
+}
+
+// FileList is a page of uploaded files
+type FileList struct {
+	Object  string       `json:"object"`
+	Data    []FileObject `json:"data"`
+	FirstID string       `json:"first_id,omitempty"`
+	LastID  string       `json:"last_id,omitempty"`
+	HasMore bool         `json:"has_more"`
+}
+
+// BatchRequest creates a batch from an uploaded JSONL file
+type BatchRequest struct {
+	InputFileID      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// Batch represents a batch job in the OpenAI API
+type Batch struct {
+	ID               string             `json:"id"`
+	Object           string             `json:"object"`
+	Endpoint         string             `json:"endpoint"`
+	Errors           *BatchErrors       `json:"errors"`
+	InputFileID      string             `json:"input_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           string             `json:"status"`
+	OutputFileID     *string            `json:"output_file_id"`
+	ErrorFileID      *string            `json:"error_file_id"`
+	CreatedAt        int64              `json:"created_at"`
+	InProgressAt     *int64             `json:"in_progress_at"`
+	ExpiresAt        *int64             `json:"expires_at"`
+	FinalizingAt     *int64             `json:"finalizing_at"`
+	CompletedAt      *int64             `json:"completed_at"`
+	FailedAt         *int64             `json:"failed_at"`
+	ExpiredAt        *int64             `json:"expired_at"`
+	CancellingAt     *int64             `json:"cancelling_at"`
+	CancelledAt      *int64             `json:"cancelled_at"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	Metadata         map[string]string  `json:"metadata"`
+}
+
+// BatchRequestCounts counts a batch's requests by outcome
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// BatchErrors lists problems found while validating a batch input file
+type BatchErrors struct {
+	Object string       `json:"object"`
+	Data   []BatchError `json:"data"`
+}
+
+// BatchError describes one invalid line of a batch input file
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Line    *int   `json:"line,omitempty"`
+}
+
+// BatchList is a page of batches
+type BatchList struct {
+	Object  string  `json:"object"`
+	Data    []Batch `json:"data"`
+	FirstID string  `json:"first_id,omitempty"`
+	LastID  string  `json:"last_id,omitempty"`
+	HasMore bool    `json:"has_more"`
+}
+
+// BatchInputLine is one request in a batch input file
+type BatchInputLine struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// BatchOutputLine is one result in a batch output or error file
+type BatchOutputLine struct {
+	ID       string             `json:"id"`
+	CustomID string             `json:"custom_id"`
+	Response *BatchLineResponse `json:"response"`
+	Error    *BatchLineError    `json:"error"`
+}
+
+// BatchLineResponse holds the HTTP response to a batch request
+type BatchLineResponse struct {
+	StatusCode int             `json:"status_code"`
+	RequestID  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// BatchLineError describes a batch request that could not be executed
+type BatchLineError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
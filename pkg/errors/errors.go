@@ -2,7 +2,12 @@ package errors
 
 import (
 	"encoding/json"
+	stderrors "errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // APIError represents a structured API error
@@ -11,6 +16,7 @@ type APIError struct {
 	Message    string `json:"message"`
 	Type       string `json:"type"`
 	StatusCode int    `json:"-"`
+	RetryAfter int    `json:"-"` // Seconds sent in the Retry-After header, omitted when 0
 }
 
 // Error implements the error interface
@@ -64,6 +70,13 @@ var (
 		StatusCode: http.StatusForbidden,
 	}
 
+	ErrPermissionDenied = &APIError{
+		Code:       "permission_denied",
+		Message:    "Permission denied",
+		Type:       TypePermission,
+		StatusCode: http.StatusForbidden,
+	}
+
 	ErrModelNotFound = &APIError{
 		Code:       "model_not_found",
 		Message:    "Model not found",
@@ -71,6 +84,13 @@ var (
 		StatusCode: http.StatusNotFound,
 	}
 
+	ErrNotFound = &APIError{
+		Code:       "not_found",
+		Message:    "Not found",
+		Type:       TypeNotFound,
+		StatusCode: http.StatusNotFound,
+	}
+
 	ErrMethodNotAllowed = &APIError{
 		Code:       "method_not_allowed",
 		Message:    "Method not allowed",
@@ -78,6 +98,13 @@ var (
 		StatusCode: http.StatusMethodNotAllowed,
 	}
 
+	ErrFileTooLarge = &APIError{
+		Code:       "file_too_large",
+		Message:    "File too large",
+		Type:       TypeInvalidRequest,
+		StatusCode: http.StatusRequestEntityTooLarge,
+	}
+
 	ErrInternalServer = &APIError{
 		Code:       "internal_server_error",
 		Message:    "Internal server error",
@@ -92,6 +119,20 @@ var (
 		StatusCode: http.StatusServiceUnavailable,
 	}
 
+	ErrContextLengthExceeded = &APIError{
+		Code:       "context_length_exceeded",
+		Message:    "Input exceeds the model's context length",
+		Type:       TypeInvalidRequest,
+		StatusCode: http.StatusBadRequest,
+	}
+
+	ErrOverloaded = &APIError{
+		Code:       "server_overloaded",
+		Message:    "The server is overloaded, please retry later",
+		Type:       TypeRateLimit,
+		StatusCode: http.StatusTooManyRequests,
+	}
+
 	ErrRequestTimeout = &APIError{
 		Code:       "request_timeout",
 		Message:    "Request timeout",
@@ -117,11 +158,69 @@ func (e *APIError) WithMessage(message string) *APIError {
 		Message:    message,
 		Type:       e.Type,
 		StatusCode: e.StatusCode,
+		RetryAfter: e.RetryAfter,
+	}
+}
+
+// UpstreamStatusError is implemented by errors reporting the HTTP status and
+// error message an upstream such as Ollama answered a request with
+type UpstreamStatusError interface {
+	error
+	UpstreamStatus() int
+	UpstreamMessage() string
+}
+
+// upstreamErrors translates upstream failures to API errors; the first entry
+// whose status (0 matches any) and message substring both match is used
+var upstreamErrors = []struct {
+	status   int
+	contains string
+	err      *APIError
+}{
+	{0, "context length", ErrContextLengthExceeded},
+	{0, "context window", ErrContextLengthExceeded},
+	{http.StatusNotFound, "", ErrModelNotFound},
+	{http.StatusBadRequest, "", ErrInvalidRequest},
+	{http.StatusTooManyRequests, "", ErrOverloaded},
+	{http.StatusServiceUnavailable, "server busy", ErrOverloaded},
+}
+
+// FromUpstream converts an error returned by an upstream call into an API error with message
+// Upstream status errors are translated with upstreamErrors, other failures are connection
+// errors. Errors implementing RetryAfter() time.Duration, such as an open circuit breaker,
+// set Retry-After
+func FromUpstream(err error, message string) *APIError {
+	apiErr := ErrOllamaConnection.WithMessage(message)
+
+	var upstream UpstreamStatusError
+	if stderrors.As(err, &upstream) {
+		status, upstreamMessage := upstream.UpstreamStatus(), strings.ToLower(upstream.UpstreamMessage())
+		for _, t := range upstreamErrors {
+			if (t.status == 0 || t.status == status) && strings.Contains(upstreamMessage, t.contains) {
+				apiErr = t.err.WithMessage(message)
+				break
+			}
+		}
+	}
+
+	var retry interface{ RetryAfter() time.Duration }
+	if stderrors.As(err, &retry) {
+		apiErr.RetryAfter = int(math.Ceil(retry.RetryAfter().Seconds()))
+	}
+
+	return apiErr
+}
+
+// SetRetryAfter sets the Retry-After header when the error carries one
+func SetRetryAfter(w http.ResponseWriter, err *APIError) {
+	if err.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(err.RetryAfter))
 	}
 }
 
 // WriteError writes an error response in OpenAI format
 func WriteError(w http.ResponseWriter, err *APIError) {
+	SetRetryAfter(w, err)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(err.StatusCode)
 
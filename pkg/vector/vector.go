@@ -0,0 +1,83 @@
+package vector
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+)
+
+// Normalize returns a copy of v scaled to unit L2 length
+// A zero vector is returned unchanged
+func Normalize(v []float64) []float64 {
+	result := make([]float64, len(v))
+	norm := Norm(v)
+	if norm == 0 {
+		copy(result, v)
+		return result
+	}
+	for i, x := range v {
+		result[i] = x / norm
+	}
+	return result
+}
+
+// Norm returns the L2 length of v
+func Norm(v []float64) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
+
+// Truncate keeps the first dims components of v and renormalizes the result,
+// as expected by Matryoshka-style embedding models
+func Truncate(v []float64, dims int) []float64 {
+	if dims >= len(v) {
+		return Normalize(v)
+	}
+	return Normalize(v[:dims])
+}
+
+// EncodeBase64 encodes v as little-endian float32 values in base64,
+// the format OpenAI uses for encoding_format=base64
+func EncodeBase64(v []float64) string {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(x)))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// Mean returns the component-wise mean of vectors, which must share a length
+func Mean(vectors [][]float64) []float64 {
+	if len(vectors) == 0 {
+		return nil
+	}
+
+	result := make([]float64, len(vectors[0]))
+	for _, v := range vectors {
+		for i := range result {
+			if i < len(v) {
+				result[i] += v[i]
+			}
+		}
+	}
+	for i := range result {
+		result[i] /= float64(len(vectors))
+	}
+	return result
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 if either is a zero vector
+func Cosine(a, b []float64) float64 {
+	var dot float64
+	for i := range min(len(a), len(b)) {
+		dot += a[i] * b[i]
+	}
+	na, nb := Norm(a), Norm(b)
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (na * nb)
+}
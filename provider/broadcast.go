@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/logger"
+)
+
+// broadcastPath reports whether a native API path manages models, which the
+// pool applies to every backend so their inventories stay in sync
+func broadcastPath(endpoint string) bool {
+	return inventoryChangingPaths[endpoint] || strings.HasPrefix(endpoint, "/api/blobs/")
+}
+
+// broadcastResult is one backend's answer to a broadcast request
+type broadcastResult struct {
+	resp *http.Response
+	body []byte
+	err  error
+}
+
+// failed reports whether the backend rejected the request, including pulls
+// that report an error in their progress stream
+func (r broadcastResult) failed() bool {
+	if r.err != nil || r.resp.StatusCode >= http.StatusBadRequest {
+		return true
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(r.body))
+	for scanner.Scan() {
+		var line struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &line) == nil && line.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// broadcast sends a model management request to every healthy backend and
+// answers with the first failure, or with the first backend's response when
+// all of them succeeded
+// Responses are collected whole, so pull progress arrives once every backend is done
+func (p *Pool) broadcast(ctx context.Context, method, path, endpoint string, body io.Reader, contentType string) (*http.Response, error) {
+	backends := p.healthySnapshot()
+	if len(backends) == 0 {
+		return nil, p.noneReachable(nil)
+	}
+
+	// Each backend reads its own copy of the body; blobs are spooled to disk
+	var open func() io.Reader
+	if strings.HasPrefix(endpoint, "/api/blobs/") {
+		spool, err := os.CreateTemp("", "ollama-blob-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to buffer blob: %w", err)
+		}
+		defer os.Remove(spool.Name())
+		defer spool.Close()
+
+		size, err := io.Copy(spool, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to buffer blob: %w", err)
+		}
+		open = func() io.Reader { return io.NewSectionReader(spool, 0, size) }
+	} else {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		open = func() io.Reader { return bytes.NewReader(data) }
+	}
+
+	results := make(map[*backend]broadcastResult, len(backends))
+	var mu sync.Mutex
+	p.eachOf(backends, func(b *backend) {
+		b.inFlight.Add(1)
+		defer b.release()
+
+		var result broadcastResult
+		result.resp, result.err = b.client.Forward(ctx, method, path, open(), contentType)
+		if result.err == nil {
+			result.body, result.err = io.ReadAll(result.resp.Body)
+			result.resp.Body.Close()
+		}
+
+		reportErr := result.err
+		if reportErr == nil && result.resp.StatusCode >= http.StatusInternalServerError {
+			reportErr = ollama.NewStatusError(result.resp.StatusCode, result.body)
+		}
+		p.report(ctx, b, reportErr)
+		if inventoryChangingPaths[endpoint] {
+			p.refresh(b)
+			p.poll(b)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		results[b] = result
+	})
+
+	chosen := results[backends[0]]
+	for _, b := range backends {
+		result := results[b]
+		if !result.failed() {
+			continue
+		}
+		reason := result.err
+		if reason == nil {
+			reason = fmt.Errorf("status %d: %s", result.resp.StatusCode, bytes.TrimSpace(result.body))
+		}
+		p.logger.Warn("Model management request failed on backend",
+			logger.String("backend", b.url),
+			logger.String("path", endpoint),
+			logger.Error(reason),
+		)
+		if !chosen.failed() {
+			chosen = result
+		}
+	}
+
+	if chosen.err != nil {
+		return nil, chosen.err
+	}
+	chosen.resp.Body = io.NopCloser(bytes.NewReader(chosen.body))
+	chosen.resp.ContentLength = int64(len(chosen.body))
+	return chosen.resp, nil
+}
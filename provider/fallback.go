@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/logger"
+)
+
+// withFallbacks runs attempt for the requested model and then, while it fails
+// with an error another model may not have, for each model of its fallback chain
+// attempt receives a copy of req naming the model to try
+func (r *Registry) withFallbacks(ctx context.Context, req *ollama.ChatRequest, attempt func(req *ollama.ChatRequest) error) error {
+	err := attempt(req)
+	for _, model := range r.cfg.GetFallbackModels(req.Model) {
+		if err == nil || !fallbackable(ctx, err) {
+			break
+		}
+
+		r.logger.Warn("Falling back to next model",
+			logger.String("model", req.Model),
+			logger.String("fallback", model),
+			logger.Error(err),
+		)
+		fallbackReq := *req
+		fallbackReq.Model = model
+		err = attempt(&fallbackReq)
+	}
+	return err
+}
+
+// fallbackable reports whether a request failing with err may succeed on
+// another model: the model is missing or overloaded, its server failed or could
+// not be reached, or its stream failed before any output
+// Other client errors, such as invalid parameters, would fail the same way
+func fallbackable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr interface{ UpstreamStatus() int }
+	if stderrors.As(err, &statusErr) {
+		status := statusErr.UpstreamStatus()
+		return status == http.StatusNotFound || status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/logger"
+)
+
+// Circuit breaker states of a backend
+const (
+	circuitClosed   = "closed"    // Requests flow normally
+	circuitOpen     = "open"      // Requests fail fast until the open duration has passed
+	circuitHalfOpen = "half_open" // A probe decides whether the circuit closes again
+)
+
+// UnavailableError reports that every backend able to serve a request has an open circuit
+type UnavailableError struct {
+	Model string
+	Wait  time.Duration // Until a probe may close one of the circuits
+}
+
+// Error implements the error interface
+func (e *UnavailableError) Error() string {
+	if e.Model == "" {
+		return "no healthy Ollama backend"
+	}
+	return fmt.Sprintf("no healthy Ollama backend for model %s", e.Model)
+}
+
+// RetryAfter returns how long the caller should wait before retrying
+func (e *UnavailableError) RetryAfter() time.Duration {
+	return e.Wait
+}
+
+// BackendStatus is the state of an Ollama backend in the pool
+type BackendStatus struct {
+	URL                 string   `json:"url"`
+	Weight              int      `json:"weight"`
+	State               string   `json:"state"` // "closed", "open" or "half_open"
+	ConsecutiveFailures int      `json:"consecutive_failures"`
+	LastError           string   `json:"last_error,omitempty"`
+	OpenedAt            int64    `json:"opened_at,omitempty"` // Unix time the circuit last opened
+	InFlight            int64    `json:"in_flight"`
+	Models              []string `json:"models"`
+	Loaded              []string `json:"loaded"`
+	Sessions            int      `json:"sessions"`      // Sticky sessions pinned to the backend
+	SessionTurns        int64    `json:"session_turns"` // Sticky session turns served
+	CacheHits           int64    `json:"cache_hits"`    // Turns estimated to have reused the KV cache
+	CachedTokens        int64    `json:"cached_tokens_estimate"`
+}
+
+// Status reports the circuit state, load, models and sticky sessions of every backend
+func (p *Pool) Status() []BackendStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sessions := make(map[*backend]int, len(p.backends))
+	for _, s := range p.sessions {
+		sessions[s.backend]++
+	}
+
+	statuses := make([]BackendStatus, 0, len(p.backends))
+	for _, b := range p.backends {
+		status := BackendStatus{
+			URL:                 b.url,
+			Weight:              b.weight,
+			State:               b.state,
+			ConsecutiveFailures: b.failures,
+			LastError:           b.lastError,
+			InFlight:            b.inFlight.Load(),
+			Models:              sortedNames(b.models),
+			Loaded:              sortedNames(b.loaded),
+			Sessions:            sessions[b],
+			SessionTurns:        b.sessionTurns,
+			CacheHits:           b.cacheHits,
+			CachedTokens:        b.cachedTokens,
+		}
+		if b.state != circuitClosed {
+			status.OpenedAt = b.openedAt.Unix()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// sortedNames returns the names in a set in order
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// probe checks a backend's health with a lightweight version request
+// Open circuits are only probed once their open duration has passed, moving them to half-open
+func (p *Pool) probe(b *backend) {
+	p.mu.Lock()
+	if b.state == circuitOpen {
+		if time.Since(b.openedAt) < p.openDuration {
+			p.mu.Unlock()
+			return
+		}
+		b.state = circuitHalfOpen
+	}
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := b.client.Version(ctx)
+	p.record(b, err)
+}
+
+// report records the outcome of a request sent to a backend
+// Only connection failures, timeouts and server errors count against the backend:
+// a 4xx response is about the request or model, and a request cancelled by the
+// caller says nothing
+func (p *Pool) report(ctx context.Context, b *backend, err error) {
+	if err != nil && (ctx.Err() != nil || !isBackendFailure(err)) {
+		err = nil
+	}
+	p.record(b, err)
+}
+
+// record updates a backend's circuit with the outcome of a request or probe
+func (p *Pool) record(b *backend, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err == nil {
+		if b.state != circuitClosed {
+			p.logger.Info("Ollama backend recovered", logger.String("backend", b.url))
+		}
+		b.state, b.failures, b.lastError = circuitClosed, 0, ""
+		return
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+	if b.state == circuitHalfOpen || (b.state == circuitClosed && b.failures >= p.failureThreshold) {
+		b.state, b.openedAt = circuitOpen, time.Now()
+		p.logger.Warn("Ollama backend circuit opened",
+			logger.String("backend", b.url),
+			logger.Int("failures", b.failures),
+			logger.String("error", b.lastError),
+		)
+	}
+}
+
+// unavailable builds the error returned when none of backends has a closed circuit,
+// retrying once the earliest of them may be probed again; p.mu must be held
+func (p *Pool) unavailable(model string, backends []*backend) *UnavailableError {
+	wait := p.openDuration
+	for _, b := range backends {
+		if remaining := p.openDuration - time.Since(b.openedAt); remaining < wait {
+			wait = remaining
+		}
+	}
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return &UnavailableError{Model: model, Wait: wait}
+}
+
+// isBackendFailure reports whether err means the backend is unreachable or failing
+func isBackendFailure(err error) bool {
+	var statusErr *ollama.StatusError
+	if stderrors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+	return isConnectionError(err)
+}
+
+// isConnectionError reports whether err means the backend could not be reached or timed out
+func isConnectionError(err error) bool {
+	var urlErr *url.Error
+	return stderrors.As(err, &urlErr)
+}
@@ -0,0 +1,18 @@
+package provider
+
+import "ollama2openai/ollama"
+
+// Provider is an upstream inference server
+// Handlers speak Ollama's API, so every provider implements ollama.ClientInterface,
+// translating requests when the upstream speaks another protocol
+type Provider interface {
+	ollama.ClientInterface
+}
+
+// Ensure the upstream clients, the pool and the registry implement Provider
+var (
+	_ Provider = (*ollama.Client)(nil)
+	_ Provider = (*OpenAIClient)(nil)
+	_ Provider = (*Pool)(nil)
+	_ Provider = (*Registry)(nil)
+)
@@ -0,0 +1,669 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"ollama2openai/openai"
+	"ollama2openai/ollama"
+)
+
+// OpenAIClient is a provider for OpenAI-compatible servers such as llama.cpp
+// server or vLLM, translating Ollama requests to the OpenAI API
+type OpenAIClient struct {
+	baseURL    string // Including the /v1 prefix
+	apiKey     string
+	httpClient *http.Client
+}
+
+// chatCompletionRequest adds the sampling options OpenAI-compatible servers accept beyond OpenAI's own
+type chatCompletionRequest struct {
+	openai.ChatCompletionRequest
+	Seed          *int           `json:"seed,omitempty"`
+	TopK          *int           `json:"top_k,omitempty"`
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+type completionRequest struct {
+	openai.CompletionRequest
+	Seed          *int           `json:"seed,omitempty"`
+	TopK          *int           `json:"top_k,omitempty"`
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatCompletionChunk is a streamed chunk, with usage on the last one
+type chatCompletionChunk struct {
+	Choices []openai.StreamChoice `json:"choices"`
+	Usage   *openai.Usage         `json:"usage"`
+}
+
+// NewOpenAIClient creates a client for the OpenAI-compatible API at baseURL
+func NewOpenAIClient(baseURL, apiKey string, timeout time.Duration) *OpenAIClient {
+	return &OpenAIClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Chat sends a non-streaming chat completion request
+func (c *OpenAIClient) Chat(ctx context.Context, req *ollama.ChatRequest) (*ollama.ChatResponse, error) {
+	chatReq := toChatCompletionRequest(req)
+	chatReq.Stream = false
+
+	resp, err := c.do(ctx, http.MethodPost, "/chat/completions", chatReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chatResp openai.ChatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(chatResp.Choices) == 0 {
+		return nil, fmt.Errorf("upstream returned no choices")
+	}
+
+	choice := chatResp.Choices[0]
+	content, _ := choice.Message.Content.(string)
+	toolCalls, err := fromOpenAIToolCalls(choice.Message.ToolCalls)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ollama.ChatResponse{
+		Model: req.Model,
+		Message: ollama.ChatMessage{
+			Role:      "assistant",
+			Content:   content,
+			ToolCalls: toolCalls,
+		},
+		Done:            true,
+		DoneReason:      doneReason(choice.FinishReason),
+		PromptEvalCount: chatResp.Usage.PromptTokens,
+		EvalCount:       chatResp.Usage.CompletionTokens,
+	}, nil
+}
+
+// ChatStream sends a streaming chat completion request, converting the SSE
+// chunks to Ollama responses
+func (c *OpenAIClient) ChatStream(ctx context.Context, req *ollama.ChatRequest) (*ollama.ChatStream, error) {
+	chatReq := toChatCompletionRequest(req)
+	chatReq.Stream = true
+	chatReq.StreamOptions = &streamOptions{IncludeUsage: true}
+
+	resp, err := c.do(ctx, http.MethodPost, "/chat/completions", chatReq)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &sseChatStream{model: req.Model, reader: bufio.NewReader(resp.Body)}
+	return ollama.NewChatStream(ctx, resp.Body, s.next), nil
+}
+
+// Embedding sends an embedding request for one or more inputs
+func (c *OpenAIClient) Embedding(ctx context.Context, req *ollama.EmbeddingRequest) (*ollama.EmbeddingResponse, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/embeddings", openai.EmbeddingRequest{
+		Model: req.Model,
+		Input: req.Input,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var embedResp struct {
+		Data []struct {
+			Embedding []float64 `json:"embedding"`
+			Index     int       `json:"index"`
+		} `json:"data"`
+		Usage openai.EmbeddingUsage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	sort.Slice(embedResp.Data, func(i, j int) bool {
+		return embedResp.Data[i].Index < embedResp.Data[j].Index
+	})
+	embeddings := make([][]float64, len(embedResp.Data))
+	for i, d := range embedResp.Data {
+		embeddings[i] = d.Embedding
+	}
+
+	return &ollama.EmbeddingResponse{
+		Model:           req.Model,
+		Embeddings:      embeddings,
+		PromptEvalCount: embedResp.Usage.PromptTokens,
+	}, nil
+}
+
+// Tags lists the models served by the upstream
+func (c *OpenAIClient) Tags(ctx context.Context) (*ollama.TagsResponse, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var models openai.ModelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	tags := &ollama.TagsResponse{Models: make([]ollama.ModelInfo, 0, len(models.Data))}
+	for _, m := range models.Data {
+		info := ollama.ModelInfo{Name: m.ID, Model: m.ID}
+		if m.Created > 0 {
+			info.ModifiedAt = time.Unix(m.Created, 0).UTC().Format(time.RFC3339)
+		}
+		tags.Models = append(tags.Models, info)
+	}
+	return tags, nil
+}
+
+// Generate sends a prompt to the legacy completions endpoint
+func (c *OpenAIClient) Generate(ctx context.Context, req *ollama.GenerateRequest) (*ollama.GenerateResponse, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/completions", toCompletionRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var completionResp openai.CompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completionResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(completionResp.Choices) == 0 {
+		return nil, fmt.Errorf("upstream returned no choices")
+	}
+
+	genResp := &ollama.GenerateResponse{
+		Model:      req.Model,
+		Response:   completionResp.Choices[0].Text,
+		Done:       true,
+		DoneReason: doneReason(completionResp.Choices[0].FinishReason),
+	}
+	if completionResp.Usage != nil {
+		genResp.PromptEvalCount = completionResp.Usage.PromptTokens
+		genResp.EvalCount = completionResp.Usage.CompletionTokens
+	}
+	return genResp, nil
+}
+
+// GenerateStream sends a prompt to the legacy completions endpoint, converting
+// the SSE chunks to Ollama generate responses
+func (c *OpenAIClient) GenerateStream(ctx context.Context, req *ollama.GenerateRequest) (*ollama.GenerateStream, error) {
+	completionReq := toCompletionRequest(req)
+	completionReq.Stream = true
+	completionReq.StreamOptions = &streamOptions{IncludeUsage: true}
+
+	resp, err := c.do(ctx, http.MethodPost, "/completions", completionReq)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &sseCompletionStream{model: req.Model, reader: bufio.NewReader(resp.Body)}
+	return ollama.NewStream(ctx, resp.Body, s.next), nil
+}
+
+// toCompletionRequest converts an Ollama generate request to a legacy completion request
+func toCompletionRequest(req *ollama.GenerateRequest) completionRequest {
+	return completionRequest{
+		CompletionRequest: openai.CompletionRequest{
+			Model:       req.Model,
+			Prompt:      req.Prompt,
+			MaxTokens:   intOption(req.Options, "num_predict"),
+			Temperature: floatOption(req.Options, "temperature"),
+			TopP:        floatOption(req.Options, "top_p"),
+			Stop:        req.Options["stop"],
+		},
+		Seed: intOption(req.Options, "seed"),
+		TopK: intOption(req.Options, "top_k"),
+	}
+}
+
+// Forward is not supported, as the upstream does not speak the native Ollama API
+func (c *OpenAIClient) Forward(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	return nil, fmt.Errorf("the native Ollama API is not available for the OpenAI-compatible upstream at %s", c.baseURL)
+}
+
+// do sends a JSON request and returns the response, which the caller must close,
+// turning non-200 responses into errors
+func (c *OpenAIClient) do(ctx context.Context, method, path string, payload interface{}) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, newStatusError(resp.StatusCode, respBody)
+	}
+
+	return resp, nil
+}
+
+// statusError is returned when an OpenAI-compatible upstream answers with a non-200 status
+type statusError struct {
+	statusCode int
+	message    string // error.message of the response body, or the raw body
+}
+
+// newStatusError creates a statusError, parsing the OpenAI {"error": {"message": "..."}} body
+func newStatusError(statusCode int, body []byte) *statusError {
+	var parsed struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.Unmarshal(body, &parsed)
+
+	message := parsed.Error.Message
+	if message == "" {
+		message = string(body)
+	}
+	return &statusError{statusCode: statusCode, message: message}
+}
+
+// Error implements the error interface
+func (e *statusError) Error() string {
+	return fmt.Sprintf("upstream returned error: %d - %s", e.statusCode, e.message)
+}
+
+// UpstreamStatus returns the HTTP status the upstream answered with
+func (e *statusError) UpstreamStatus() int {
+	return e.statusCode
+}
+
+// UpstreamMessage returns the upstream's error message
+func (e *statusError) UpstreamMessage() string {
+	return e.message
+}
+
+// toChatCompletionRequest converts an Ollama chat request to the OpenAI format
+func toChatCompletionRequest(req *ollama.ChatRequest) *chatCompletionRequest {
+	chatReq := &chatCompletionRequest{
+		ChatCompletionRequest: openai.ChatCompletionRequest{
+			Model:       req.Model,
+			MaxTokens:   intOption(req.Options, "num_predict"),
+			Temperature: floatOption(req.Options, "temperature"),
+			TopP:        floatOption(req.Options, "top_p"),
+			Stop:        req.Options["stop"],
+		},
+		Seed: intOption(req.Options, "seed"),
+		TopK: intOption(req.Options, "top_k"),
+	}
+
+	if req.Format == "json" {
+		chatReq.ResponseFormat = &openai.ResponseFormat{Type: "json_object"}
+	}
+
+	for _, tool := range req.Tools {
+		chatReq.Tools = append(chatReq.Tools, openai.Tool{
+			Type: "function",
+			Function: &openai.ToolFunc{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  tool.Function.Parameters,
+			},
+		})
+	}
+
+	// Ollama matches tool results to calls by function name, OpenAI by call ID,
+	// so IDs are assigned to calls and handed to results in order
+	pending := make(map[string][]string)
+	calls := 0
+
+	for _, msg := range req.Messages {
+		chatMsg := openai.ChatMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		}
+
+		if len(msg.Images) > 0 {
+			parts := make([]openai.ContentPart, 0, 1+len(msg.Images))
+			if msg.Content != "" {
+				parts = append(parts, openai.ContentPart{Type: "text", Text: msg.Content})
+			}
+			for _, image := range msg.Images {
+				parts = append(parts, openai.ContentPart{
+					Type:     "image_url",
+					ImageURL: &openai.ImageURL{URL: imageDataURL(image)},
+				})
+			}
+			chatMsg.Content = parts
+		}
+
+		for _, call := range msg.ToolCalls {
+			calls++
+			id := fmt.Sprintf("call_%d", calls)
+			pending[call.Function.Name] = append(pending[call.Function.Name], id)
+
+			arguments := "{}"
+			if call.Function.Arguments != nil {
+				data, _ := json.Marshal(call.Function.Arguments)
+				arguments = string(data)
+			}
+			chatMsg.ToolCalls = append(chatMsg.ToolCalls, openai.ToolCall{
+				ID:   id,
+				Type: "function",
+				Function: &openai.ToolCallFunction{
+					Name:      call.Function.Name,
+					Arguments: arguments,
+				},
+			})
+		}
+
+		if msg.Role == "tool" {
+			if ids := pending[msg.ToolName]; len(ids) > 0 {
+				chatMsg.ToolCallID = ids[0]
+				pending[msg.ToolName] = ids[1:]
+			}
+		}
+
+		chatReq.Messages = append(chatReq.Messages, chatMsg)
+	}
+
+	return chatReq
+}
+
+// fromOpenAIToolCalls converts OpenAI tool calls, whose arguments are a JSON string
+func fromOpenAIToolCalls(calls []openai.ToolCall) ([]ollama.ToolCall, error) {
+	var result []ollama.ToolCall
+	for _, call := range calls {
+		if call.Function == nil {
+			continue
+		}
+		args, err := parseArguments(call.Function.Arguments)
+		if err != nil {
+			return nil, fmt.Errorf("invalid arguments for tool call %s: %w", call.Function.Name, err)
+		}
+		result = append(result, ollama.ToolCall{
+			Function: ollama.ToolCallFunction{
+				Name:      call.Function.Name,
+				Arguments: args,
+			},
+		})
+	}
+	return result, nil
+}
+
+func parseArguments(arguments string) (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	if strings.TrimSpace(arguments) == "" {
+		return args, nil
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// sseChatStream reads an OpenAI SSE chat stream
+// Tool call arguments arrive in pieces, so tool calls are sent with the final response
+type sseChatStream struct {
+	model        string
+	reader       *bufio.Reader
+	calls        []*streamedToolCall
+	finishReason string
+	usage        openai.Usage
+	finished     bool
+}
+
+type streamedToolCall struct {
+	name      string
+	arguments strings.Builder
+}
+
+// next returns the next response with content, or the final response at the end of the stream
+func (s *sseChatStream) next() (ollama.ChatResponse, error) {
+	for {
+		if s.finished {
+			return ollama.ChatResponse{}, io.EOF
+		}
+
+		line, err := s.reader.ReadString('\n')
+		if err != nil && line == "" {
+			if err == io.EOF {
+				return s.done()
+			}
+			return ollama.ChatResponse{}, err
+		}
+
+		data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return s.done()
+		}
+
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return ollama.ChatResponse{}, fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			s.usage = *chunk.Usage
+		}
+
+		var content strings.Builder
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != "" {
+				s.finishReason = choice.FinishReason
+			}
+			if text, ok := choice.Delta.Content.(string); ok {
+				content.WriteString(text)
+			}
+			for _, tc := range choice.Delta.ToolCalls {
+				s.addToolCallDelta(tc)
+			}
+		}
+
+		if content.Len() > 0 {
+			return ollama.ChatResponse{
+				Model:   s.model,
+				Message: ollama.ChatMessage{Role: "assistant", Content: content.String()},
+			}, nil
+		}
+	}
+}
+
+func (s *sseChatStream) addToolCallDelta(tc openai.ToolCall) {
+	index := len(s.calls) - 1
+	if tc.Index != nil {
+		index = *tc.Index
+	} else if tc.ID != "" || index < 0 {
+		index = len(s.calls)
+	}
+	for len(s.calls) <= index {
+		s.calls = append(s.calls, &streamedToolCall{})
+	}
+
+	call := s.calls[index]
+	if tc.Function != nil {
+		if call.name == "" {
+			call.name = tc.Function.Name
+		}
+		call.arguments.WriteString(tc.Function.Arguments)
+	}
+}
+
+// done builds the final response with the collected tool calls and usage
+func (s *sseChatStream) done() (ollama.ChatResponse, error) {
+	s.finished = true
+
+	var toolCalls []ollama.ToolCall
+	for _, call := range s.calls {
+		args, err := parseArguments(call.arguments.String())
+		if err != nil {
+			return ollama.ChatResponse{}, fmt.Errorf("invalid arguments for tool call %s: %w", call.name, err)
+		}
+		toolCalls = append(toolCalls, ollama.ToolCall{
+			Function: ollama.ToolCallFunction{Name: call.name, Arguments: args},
+		})
+	}
+
+	return ollama.ChatResponse{
+		Model:           s.model,
+		Message:         ollama.ChatMessage{Role: "assistant", ToolCalls: toolCalls},
+		Done:            true,
+		DoneReason:      doneReason(s.finishReason),
+		PromptEvalCount: s.usage.PromptTokens,
+		EvalCount:       s.usage.CompletionTokens,
+	}, nil
+}
+
+// sseCompletionStream reads an OpenAI SSE legacy completion stream
+type sseCompletionStream struct {
+	model        string
+	reader       *bufio.Reader
+	finishReason string
+	usage        openai.Usage
+	finished     bool
+}
+
+// next returns the next response with text, or the final response at the end of the stream
+func (s *sseCompletionStream) next() (ollama.GenerateResponse, error) {
+	for {
+		if s.finished {
+			return ollama.GenerateResponse{}, io.EOF
+		}
+
+		line, err := s.reader.ReadString('\n')
+		if err != nil && line == "" {
+			if err == io.EOF {
+				return s.done(), nil
+			}
+			return ollama.GenerateResponse{}, err
+		}
+
+		data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return s.done(), nil
+		}
+
+		var chunk openai.CompletionResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return ollama.GenerateResponse{}, fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			s.usage = *chunk.Usage
+		}
+
+		var text strings.Builder
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != "" {
+				s.finishReason = choice.FinishReason
+			}
+			text.WriteString(choice.Text)
+		}
+
+		if text.Len() > 0 {
+			return ollama.GenerateResponse{Model: s.model, Response: text.String()}, nil
+		}
+	}
+}
+
+// done builds the final response with the collected usage
+func (s *sseCompletionStream) done() ollama.GenerateResponse {
+	s.finished = true
+	return ollama.GenerateResponse{
+		Model:           s.model,
+		Done:            true,
+		DoneReason:      doneReason(s.finishReason),
+		PromptEvalCount: s.usage.PromptTokens,
+		EvalCount:       s.usage.CompletionTokens,
+	}
+}
+
+// doneReason maps an OpenAI finish reason to Ollama's done reason
+func doneReason(finishReason string) string {
+	if finishReason == "length" {
+		return "length"
+	}
+	return "stop"
+}
+
+// imageDataURL wraps Ollama's bare base64 image data in a data URL,
+// recognising the common formats by their leading bytes
+func imageDataURL(data string) string {
+	mediaType := "image/jpeg"
+	switch {
+	case strings.HasPrefix(data, "iVBOR"):
+		mediaType = "image/png"
+	case strings.HasPrefix(data, "R0lG"):
+		mediaType = "image/gif"
+	case strings.HasPrefix(data, "UklG"):
+		mediaType = "image/webp"
+	}
+	return fmt.Sprintf("data:%s;base64,%s", mediaType, data)
+}
+
+// floatOption reads a numeric Ollama option
+func floatOption(options map[string]interface{}, key string) *float64 {
+	switch v := options[key].(type) {
+	case float64:
+		return &v
+	case float32:
+		f := float64(v)
+		return &f
+	case int:
+		f := float64(v)
+		return &f
+	}
+	return nil
+}
+
+// intOption reads an integer Ollama option
+func intOption(options map[string]interface{}, key string) *int {
+	switch v := options[key].(type) {
+	case int:
+		return &v
+	case float64:
+		i := int(v)
+		return &i
+	}
+	return nil
+}
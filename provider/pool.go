@@ -0,0 +1,682 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ollama2openai/config"
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/logger"
+)
+
+// inventoryChangingPaths are the native API paths that add or remove models,
+// after which the backend's inventory is refreshed
+var inventoryChangingPaths = map[string]bool{
+	"/api/pull":   true,
+	"/api/create": true,
+	"/api/copy":   true,
+	"/api/delete": true,
+}
+
+// Pool spreads requests over several Ollama servers, sending each request
+// only to healthy backends whose model inventory contains the requested model
+// and preferring those that already have it loaded
+type Pool struct {
+	backends         []*backend
+	leastLoaded      bool // Prefer backends with the fewest in-flight requests per weight
+	failureThreshold int
+	openDuration     time.Duration
+	maxAttempts      int
+	initialBackoff   time.Duration
+	maxBackoff       time.Duration
+	sticky           bool // Pin conversations to the backend holding their KV cache
+	sessionTTL       time.Duration
+	logger           logger.Logger
+
+	mu       sync.Mutex // Guards the inventories, loaded models, circuits, sessions and round-robin state
+	sessions map[string]*session
+}
+
+// backend is an Ollama server in the pool
+type backend struct {
+	url      string
+	weight   int
+	client   *ollama.Client
+	inFlight atomic.Int64
+
+	current int             // Smooth weighted round-robin state
+	models  map[string]bool // Normalized model names, nil until listed
+	loaded  map[string]bool // Normalized names of the models in memory
+
+	state     string // Circuit breaker state
+	failures  int    // Consecutive failed requests or probes
+	lastError string
+	openedAt  time.Time
+
+	sessionTurns int64 // Sticky session turns served, with the KV cache reuse estimated from them
+	cacheHits    int64
+	cachedTokens int64
+}
+
+// NewPool creates a pool of the configured Ollama backends, loads their model
+// inventories and loaded models and keeps them and the backends' health checked
+// in the background
+func NewPool(cfg *config.Config, log logger.Logger) *Pool {
+	p := &Pool{
+		leastLoaded:      cfg.LoadBalancing.Strategy == "least_in_flight",
+		failureThreshold: cfg.HealthCheck.FailureThreshold,
+		openDuration:     cfg.GetCircuitOpenDuration(),
+		maxAttempts:      cfg.Retry.MaxAttempts,
+		initialBackoff:   cfg.GetInitialBackoff(),
+		maxBackoff:       cfg.GetMaxBackoff(),
+		sticky:           cfg.LoadBalancing.StickySessions,
+		sessionTTL:       cfg.GetSessionTTL(),
+		logger:           log,
+		sessions:         make(map[string]*session),
+	}
+	for _, bc := range cfg.OllamaBackends {
+		p.backends = append(p.backends, &backend{
+			url:    bc.URL,
+			weight: bc.Weight,
+			client: ollama.NewClient(bc.URL, cfg.GetTimeout()),
+			state:  circuitClosed,
+		})
+	}
+
+	p.each(p.refresh)
+	p.each(p.poll)
+	go func() {
+		for range time.Tick(cfg.GetInventoryRefresh()) {
+			p.each(p.refresh)
+		}
+	}()
+	go func() {
+		for range time.Tick(cfg.GetLoadedRefresh()) {
+			p.each(p.poll)
+		}
+	}()
+	go func() {
+		for range time.Tick(cfg.GetHealthCheckInterval()) {
+			p.each(p.probe)
+		}
+	}()
+	if p.sticky {
+		go func() {
+			for range time.Tick(p.sessionTTL) {
+				p.expireSessions()
+			}
+		}()
+	}
+
+	return p
+}
+
+// each calls fn for every backend concurrently and waits for all calls to finish
+func (p *Pool) each(fn func(b *backend)) {
+	p.eachOf(p.backends, fn)
+}
+
+// eachOf calls fn for each of backends concurrently and waits for all calls to finish
+func (p *Pool) eachOf(backends []*backend, fn func(b *backend)) {
+	var wg sync.WaitGroup
+	for _, b := range backends {
+		wg.Add(1)
+		go func(b *backend) {
+			defer wg.Done()
+			fn(b)
+		}(b)
+	}
+	wg.Wait()
+}
+
+// refresh reloads a backend's model inventory, keeping the previous one if the call fails
+func (p *Pool) refresh(b *backend) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if tags, err := b.client.Tags(ctx); err == nil {
+		p.setInventory(b, tags.Models)
+	}
+}
+
+// setInventory replaces a backend's model inventory
+func (p *Pool) setInventory(b *backend, models []ollama.ModelInfo) {
+	names := make(map[string]bool, len(models))
+	for _, m := range models {
+		names[ollama.NormalizeModelName(m.Name)] = true
+	}
+
+	p.mu.Lock()
+	b.models = names
+	p.mu.Unlock()
+}
+
+// poll reloads the models a backend has in memory, keeping the previous ones if the call fails
+func (p *Pool) poll(b *backend) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if ps, err := b.client.Ps(ctx); err == nil {
+		p.setLoaded(b, ps.Models)
+	}
+}
+
+// setLoaded replaces the set of models a backend has in memory
+func (p *Pool) setLoaded(b *backend, models []ollama.RunningModel) {
+	names := make(map[string]bool, len(models))
+	for _, m := range models {
+		names[ollama.NormalizeModelName(m.Name)] = true
+	}
+
+	p.mu.Lock()
+	b.loaded = names
+	p.mu.Unlock()
+}
+
+// pick selects the backend for a request for model and counts it as in flight;
+// the caller must call release once the request has finished
+// Only backends with a closed circuit are used, failing fast when none can serve
+// the model. Backends with the model loaded are preferred; otherwise the least
+// loaded backend listing it loads the model. When no backend lists the model,
+// every healthy backend is a candidate so Ollama reports the missing model
+// Backends in exclude, which already failed the request, are only used again
+// when no other backend can serve it
+// A request of a sticky session goes to the backend the session is pinned to
+// while it can serve the model, and is pinned to the chosen backend otherwise
+func (p *Pool) pick(model, session string, exclude map[*backend]bool) (*backend, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var best *backend
+	reason := "sticky"
+	if session != "" {
+		best = p.pinned(session, model, exclude)
+	}
+	if best == nil {
+		var err error
+		if best, reason, err = p.choose(model, exclude); err != nil {
+			return nil, err
+		}
+		if session != "" {
+			p.pin(session, best)
+		}
+	}
+
+	inFlight := best.inFlight.Add(1)
+	p.logger.Debug("Routed request to Ollama backend",
+		logger.String("model", model),
+		logger.String("backend", best.url),
+		logger.String("reason", reason),
+		logger.Int64("in_flight", inFlight),
+	)
+	return best, nil
+}
+
+// choose implements pick, returning the backend and why it was chosen; p.mu must be held
+func (p *Pool) choose(model string, exclude map[*backend]bool) (*backend, string, error) {
+	usable := func(backends []*backend) []*backend {
+		return filterBackends(p.healthy(backends), func(b *backend) bool { return !exclude[b] })
+	}
+
+	name := ollama.NormalizeModelName(model)
+	candidates, reason := usable(p.backends), "no model"
+	failing := p.backends
+	if model != "" {
+		serving := filterBackends(p.backends, func(b *backend) bool { return b.models[name] })
+		healthy := usable(serving)
+		warm := filterBackends(healthy, func(b *backend) bool { return b.loaded[name] })
+		switch {
+		case len(warm) > 0:
+			candidates, reason = warm, "warm"
+		case len(healthy) > 0:
+			candidates, reason = healthy, "cold"
+		case len(serving) > 0:
+			candidates, failing = nil, serving
+		default:
+			reason = "not listed"
+		}
+	}
+	if len(candidates) == 0 {
+		if len(exclude) > 0 {
+			return p.choose(model, nil)
+		}
+		return nil, "", p.unavailable(model, failing)
+	}
+
+	if p.leastLoaded || reason == "cold" {
+		candidates = leastLoaded(candidates)
+	}
+
+	// Smooth weighted round-robin over the remaining candidates
+	var best *backend
+	total := 0
+	for _, b := range candidates {
+		b.current += b.weight
+		total += b.weight
+		if best == nil || b.current > best.current {
+			best = b
+		}
+	}
+	best.current -= total
+
+	// Ollama loads the model now, so later requests can follow it before the next poll
+	if reason == "cold" {
+		if best.loaded == nil {
+			best.loaded = make(map[string]bool)
+		}
+		best.loaded[name] = true
+	}
+
+	if len(exclude) > 0 {
+		reason += ", failover"
+	}
+	return best, reason, nil
+}
+
+// healthy returns the backends whose circuit is closed; p.mu must be held
+func (p *Pool) healthy(backends []*backend) []*backend {
+	return filterBackends(backends, func(b *backend) bool { return b.state == circuitClosed })
+}
+
+// healthySnapshot returns the backends whose circuit is closed
+func (p *Pool) healthySnapshot() []*backend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.healthy(p.backends)
+}
+
+// filterBackends returns the backends matching keep
+func filterBackends(backends []*backend, keep func(b *backend) bool) []*backend {
+	var matched []*backend
+	for _, b := range backends {
+		if keep(b) {
+			matched = append(matched, b)
+		}
+	}
+	return matched
+}
+
+// leastLoaded returns the backends with the fewest in-flight requests per unit of weight
+func leastLoaded(backends []*backend) []*backend {
+	var least []*backend
+	for _, b := range backends {
+		if len(least) == 0 {
+			least = append(least, b)
+			continue
+		}
+		// Compare inFlight/weight without division
+		load, minLoad := b.inFlight.Load()*int64(least[0].weight), least[0].inFlight.Load()*int64(b.weight)
+		switch {
+		case load < minLoad:
+			least = []*backend{b}
+		case load == minLoad:
+			least = append(least, b)
+		}
+	}
+	return least
+}
+
+// release marks a request picked for the backend as finished
+func (b *backend) release() {
+	b.inFlight.Add(-1)
+}
+
+// Chat sends a non-streaming chat request to a backend serving the model
+func (p *Pool) Chat(ctx context.Context, req *ollama.ChatRequest) (*ollama.ChatResponse, error) {
+	session := p.sessionKey(ctx, req)
+	previous, current := p.turnHashes(req)
+	var resp *ollama.ChatResponse
+	err := p.do(ctx, req.Model, session, p.maxAttempts, func(b *backend) error {
+		defer b.release()
+		var err error
+		if resp, err = b.client.Chat(ctx, req); err == nil {
+			p.recordTurn(session, previous, current, b, resp.PromptEvalCount, resp.EvalCount)
+		}
+		return err
+	})
+	return resp, err
+}
+
+// ChatStream sends a streaming chat request to a backend serving the model,
+// which counts as in flight until the stream ends
+// The first response is awaited before returning, so a backend failing before
+// any output can be replaced by another one
+func (p *Pool) ChatStream(ctx context.Context, req *ollama.ChatRequest) (*ollama.ChatStream, error) {
+	session := p.sessionKey(ctx, req)
+	previous, current := p.turnHashes(req)
+	return openStream(ctx, p, req.Model, session, func(b *backend) (*ollama.ChatStream, error) {
+		return b.client.ChatStream(ctx, req)
+	}, func(b *backend, resp ollama.ChatResponse) {
+		if resp.Done {
+			p.recordTurn(session, previous, current, b, resp.PromptEvalCount, resp.EvalCount)
+		}
+	})
+}
+
+// GenerateStream sends a streaming generate request to a backend serving the
+// model, like ChatStream
+func (p *Pool) GenerateStream(ctx context.Context, req *ollama.GenerateRequest) (*ollama.GenerateStream, error) {
+	return openStream(ctx, p, req.Model, "", func(b *backend) (*ollama.GenerateStream, error) {
+		return b.client.GenerateStream(ctx, req)
+	}, nil)
+}
+
+// openStream implements ChatStream and GenerateStream, opening a stream with
+// open on the backend picked for each attempt; observe, when set, sees each
+// response read from the backend
+func openStream[T ollama.StreamResponse](ctx context.Context, p *Pool, model, session string, open func(b *backend) (*ollama.Stream[T], error), observe func(b *backend, resp T)) (*ollama.Stream[T], error) {
+	var result *ollama.Stream[T]
+	err := p.do(ctx, model, session, p.maxAttempts, func(b *backend) error {
+		stream, err := open(b)
+		if err != nil {
+			b.release()
+			return err
+		}
+
+		first, err := stream.ReadResponse()
+		if err != nil && err != io.EOF {
+			stream.Close()
+			b.release()
+			return &streamStartError{err: err}
+		}
+
+		// Replay the first response, or the end of an empty stream, before the rest
+		replayed := false
+		next := func() (T, error) {
+			resp, err := first, err
+			if replayed {
+				resp, err = stream.ReadResponse()
+			}
+			replayed = true
+			if err == nil && observe != nil {
+				observe(b, resp)
+			}
+			return resp, err
+		}
+		result = ollama.NewStream(ctx, releaseCloser(b, func() error {
+			stream.Close()
+			return nil
+		}), next)
+		return nil
+	})
+	return result, err
+}
+
+// Embedding sends an embedding request to a backend serving the model
+func (p *Pool) Embedding(ctx context.Context, req *ollama.EmbeddingRequest) (*ollama.EmbeddingResponse, error) {
+	var resp *ollama.EmbeddingResponse
+	err := p.do(ctx, req.Model, "", p.maxAttempts, func(b *backend) error {
+		defer b.release()
+		var err error
+		resp, err = b.client.Embedding(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// Generate sends a generate request to a backend serving the model
+func (p *Pool) Generate(ctx context.Context, req *ollama.GenerateRequest) (*ollama.GenerateResponse, error) {
+	var resp *ollama.GenerateResponse
+	err := p.do(ctx, req.Model, "", p.maxAttempts, func(b *backend) error {
+		defer b.release()
+		var err error
+		resp, err = b.client.Generate(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// Tags lists the models of every healthy backend, refreshing their inventories
+// A model available on several backends is listed once
+func (p *Pool) Tags(ctx context.Context) (*ollama.TagsResponse, error) {
+	backends := p.healthySnapshot()
+	results := make(map[*backend]*ollama.TagsResponse, len(backends))
+	var lastErr error
+
+	var mu sync.Mutex
+	p.eachOf(backends, func(b *backend) {
+		tags, err := b.client.Tags(ctx)
+		p.report(ctx, b, err)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			lastErr = err
+			return
+		}
+		results[b] = tags
+		p.setInventory(b, tags.Models)
+	})
+
+	var models []ollama.ModelInfo
+	seen := make(map[string]bool)
+	for _, b := range backends {
+		tags, ok := results[b]
+		if !ok {
+			continue
+		}
+		for _, m := range tags.Models {
+			name := ollama.NormalizeModelName(m.Name)
+			if !seen[name] {
+				seen[name] = true
+				models = append(models, m)
+			}
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, p.noneReachable(lastErr)
+	}
+	return &ollama.TagsResponse{Models: models}, nil
+}
+
+// Ps lists the models loaded on every healthy backend, refreshing the pool's view of them
+// A model loaded on several backends is listed once per backend
+func (p *Pool) Ps(ctx context.Context) (*ollama.PsResponse, error) {
+	backends := p.healthySnapshot()
+	results := make(map[*backend]*ollama.PsResponse, len(backends))
+	var lastErr error
+
+	var mu sync.Mutex
+	p.eachOf(backends, func(b *backend) {
+		ps, err := b.client.Ps(ctx)
+		p.report(ctx, b, err)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			lastErr = err
+			return
+		}
+		results[b] = ps
+		p.setLoaded(b, ps.Models)
+	})
+
+	models := []ollama.RunningModel{}
+	for _, b := range backends {
+		if ps, ok := results[b]; ok {
+			models = append(models, ps.Models...)
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, p.noneReachable(lastErr)
+	}
+	return &ollama.PsResponse{Models: models}, nil
+}
+
+// noneReachable builds the error returned when no backend answered a pool-wide request
+func (p *Pool) noneReachable(lastErr error) error {
+	if lastErr == nil {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.unavailable("", p.backends)
+	}
+	return fmt.Errorf("no Ollama backend is reachable: %w", lastErr)
+}
+
+// Forward relays a native API request to a backend serving the model named in
+// its body; /api/tags and /api/ps are answered for the whole pool, and model
+// management requests are sent to every backend
+func (p *Pool) Forward(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	endpoint, _, _ := strings.Cut(path, "?")
+	if method == http.MethodGet {
+		switch endpoint {
+		case "/api/tags":
+			tags, err := p.Tags(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return jsonResponse(tags)
+		case "/api/ps":
+			ps, err := p.Ps(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return jsonResponse(ps)
+		}
+	}
+
+	// With several backends, model management applies to all of them
+	if len(p.backends) > 1 && broadcastPath(endpoint) {
+		return p.broadcast(ctx, method, path, endpoint, body, contentType)
+	}
+
+	// Blob uploads can be large and name no model, so they are streamed through
+	var model string
+	var data []byte
+	buffered := !strings.HasPrefix(endpoint, "/api/blobs/")
+	if buffered {
+		var err error
+		if model, data, err = peekModel(body); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only reads and inference are retried; model management must not be repeated elsewhere
+	attempts := 1
+	if buffered && (method == http.MethodGet || nativeModelPaths[endpoint]) {
+		attempts = p.maxAttempts
+	}
+
+	var resp *http.Response
+	err := p.do(ctx, model, "", attempts, func(b *backend) error {
+		reqBody := body
+		if buffered {
+			reqBody = bytes.NewReader(data)
+		}
+
+		r, err := b.client.Forward(ctx, method, path, reqBody, contentType)
+		if err != nil {
+			b.release()
+			return err
+		}
+
+		// Server errors are buffered so the last one can be relayed when no retry succeeds
+		if r.StatusCode >= http.StatusInternalServerError {
+			errBody, _ := io.ReadAll(r.Body)
+			r.Body.Close()
+			b.release()
+			r.Body = io.NopCloser(bytes.NewReader(errBody))
+			resp = r
+			return ollama.NewStatusError(r.StatusCode, errBody)
+		}
+
+		changesInventory := inventoryChangingPaths[endpoint]
+		upstreamBody := r.Body
+		r.Body = &readCloser{Reader: upstreamBody, closer: releaseCloser(b, func() error {
+			err := upstreamBody.Close()
+			if changesInventory {
+				go func() {
+					p.refresh(b)
+					p.poll(b)
+				}()
+			}
+			return err
+		})}
+		resp = r
+		return nil
+	})
+	if resp == nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// jsonResponse builds a successful native API response answered by the pool itself
+func jsonResponse(v interface{}) (*http.Response, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+	}, nil
+}
+
+// peekModel reads a native API request body and the model it names; older
+// clients name it "name" instead of "model"
+func peekModel(body io.Reader) (string, []byte, error) {
+	if body == nil {
+		return "", nil, nil
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read request: %w", err)
+	}
+
+	var req struct {
+		Model string `json:"model"`
+		Name  string `json:"name"`
+	}
+	json.Unmarshal(data, &req)
+
+	model := req.Model
+	if model == "" {
+		model = req.Name
+	}
+	return model, data, nil
+}
+
+// readCloser pairs a reader with a custom close
+type readCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+// Close closes the underlying closer
+func (r *readCloser) Close() error {
+	return r.closer.Close()
+}
+
+// closerFunc adapts a function to io.Closer
+type closerFunc func() error
+
+// Close calls the function
+func (f closerFunc) Close() error {
+	return f()
+}
+
+// releaseCloser returns a closer running close and then releasing the backend, at most once
+func releaseCloser(b *backend, close func() error) io.Closer {
+	var once sync.Once
+	return closerFunc(func() error {
+		var err error
+		once.Do(func() {
+			err = close()
+			b.release()
+		})
+		return err
+	})
+}
@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"ollama2openai/config"
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/logger"
+)
+
+// nativeModelPaths are the native API paths whose JSON body names a model
+var nativeModelPaths = map[string]bool{
+	"/api/chat":       true,
+	"/api/generate":   true,
+	"/api/embed":      true,
+	"/api/embeddings": true,
+	"/api/show":       true,
+}
+
+// Registry routes each request to the provider configured for its model,
+// sending other models to the pool of Ollama backends
+// Chat requests failing before any output move down the model's fallback chain
+type Registry struct {
+	fallback  Provider
+	upstreams []upstream          // In config order
+	routes    map[string]Provider // Normalized model name -> provider
+	cfg       *config.Config
+	logger    logger.Logger
+}
+
+// upstream is a configured provider with the models routed to it
+type upstream struct {
+	provider Provider
+	models   []string
+}
+
+// NewRegistry creates the providers described in the configuration,
+// sending models not routed to any of them to pool
+func NewRegistry(cfg *config.Config, pool *Pool, log logger.Logger) *Registry {
+	r := &Registry{
+		fallback: pool,
+		routes:   make(map[string]Provider),
+		cfg:      cfg,
+		logger:   log,
+	}
+
+	for _, pc := range cfg.Providers {
+		var p Provider
+		switch pc.Type {
+		case "openai":
+			p = NewOpenAIClient(pc.URL, pc.APIKey, cfg.GetTimeout())
+		default:
+			p = ollama.NewClient(pc.URL, cfg.GetTimeout())
+		}
+		r.upstreams = append(r.upstreams, upstream{provider: p, models: pc.Models})
+
+		for _, model := range pc.Models {
+			r.routes[ollama.NormalizeModelName(model)] = p
+		}
+	}
+
+	return r
+}
+
+// provider returns the provider serving model
+func (r *Registry) provider(model string) Provider {
+	if p, ok := r.routes[ollama.NormalizeModelName(model)]; ok {
+		return p
+	}
+	return r.fallback
+}
+
+// Chat sends a non-streaming chat request to the model's provider, or to the
+// provider of the first model of its fallback chain able to serve it
+// The response names the model that served the request
+func (r *Registry) Chat(ctx context.Context, req *ollama.ChatRequest) (*ollama.ChatResponse, error) {
+	var resp *ollama.ChatResponse
+	err := r.withFallbacks(ctx, req, func(req *ollama.ChatRequest) error {
+		var err error
+		if resp, err = r.provider(req.Model).Chat(ctx, req); err == nil {
+			resp.Model = req.Model
+		}
+		return err
+	})
+	return resp, err
+}
+
+// ChatStream sends a streaming chat request to the model's provider, or to the
+// provider of the first model of its fallback chain able to serve it
+// The stream records the model serving it
+func (r *Registry) ChatStream(ctx context.Context, req *ollama.ChatRequest) (*ollama.ChatStream, error) {
+	var stream *ollama.ChatStream
+	err := r.withFallbacks(ctx, req, func(req *ollama.ChatRequest) error {
+		var err error
+		if stream, err = r.provider(req.Model).ChatStream(ctx, req); err == nil {
+			stream.SetModel(req.Model)
+		}
+		return err
+	})
+	return stream, err
+}
+
+// Embedding sends an embedding request to the model's provider
+func (r *Registry) Embedding(ctx context.Context, req *ollama.EmbeddingRequest) (*ollama.EmbeddingResponse, error) {
+	return r.provider(req.Model).Embedding(ctx, req)
+}
+
+// Generate sends a generate request to the model's provider
+func (r *Registry) Generate(ctx context.Context, req *ollama.GenerateRequest) (*ollama.GenerateResponse, error) {
+	return r.provider(req.Model).Generate(ctx, req)
+}
+
+// GenerateStream sends a streaming generate request to the model's provider
+// The stream records the model serving it
+func (r *Registry) GenerateStream(ctx context.Context, req *ollama.GenerateRequest) (*ollama.GenerateStream, error) {
+	stream, err := r.provider(req.Model).GenerateStream(ctx, req)
+	if err == nil {
+		stream.SetModel(req.Model)
+	}
+	return stream, err
+}
+
+// Tags lists the Ollama backends' models together with the models configured
+// for the other providers, with details when the provider lists them
+func (r *Registry) Tags(ctx context.Context) (*ollama.TagsResponse, error) {
+	tags, err := r.fallback.Tags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Models routed elsewhere are listed with their own provider
+	models := make([]ollama.ModelInfo, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		if _, routed := r.routes[ollama.NormalizeModelName(m.Name)]; !routed {
+			models = append(models, m)
+		}
+	}
+
+	for _, u := range r.upstreams {
+		listed := make(map[string]ollama.ModelInfo)
+		if upstreamTags, err := u.provider.Tags(ctx); err == nil {
+			for _, m := range upstreamTags.Models {
+				listed[ollama.NormalizeModelName(m.Name)] = m
+			}
+		}
+
+		for _, model := range u.models {
+			info, ok := listed[ollama.NormalizeModelName(model)]
+			if !ok {
+				info = ollama.ModelInfo{Name: model, Model: model}
+			}
+			models = append(models, info)
+		}
+	}
+
+	return &ollama.TagsResponse{Models: models}, nil
+}
+
+// Forward relays a native API request, choosing the provider by the model
+// named in the body of model requests and the Ollama backends otherwise
+func (r *Registry) Forward(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	endpoint, _, _ := strings.Cut(path, "?")
+	if !nativeModelPaths[endpoint] || body == nil {
+		return r.fallback.Forward(ctx, method, path, body, contentType)
+	}
+
+	model, data, err := peekModel(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.provider(model).Forward(ctx, method, path, bytes.NewReader(data), contentType)
+}
@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"context"
+	stderrors "errors"
+	"math/rand/v2"
+	"time"
+
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/logger"
+)
+
+// streamStartError is a stream failure before its first response, which can
+// be retried since nothing has reached the client yet
+type streamStartError struct {
+	err error
+}
+
+// Error implements the error interface
+func (e *streamStartError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying stream error
+func (e *streamStartError) Unwrap() error {
+	return e.err
+}
+
+// do runs attempt on a backend serving model, retrying retryable failures up to
+// attempts times in total with jittered backoff, each time on a backend not
+// tried yet when one can serve the model
+// session names the sticky session of the request, or is empty
+// attempt must release the backend, immediately on failure or once its result is closed
+func (p *Pool) do(ctx context.Context, model, session string, attempts int, attempt func(b *backend) error) error {
+	tried := make(map[*backend]bool)
+	var lastErr error
+
+	for n := 1; ; n++ {
+		b, err := p.pick(model, session, tried)
+		if err != nil {
+			// Circuits opened by the previous attempts say less than their errors
+			if lastErr != nil {
+				return lastErr
+			}
+			return err
+		}
+
+		err = attempt(b)
+		p.report(ctx, b, err)
+		if err == nil {
+			return nil
+		}
+		if n >= attempts || !retryable(ctx, err) {
+			return err
+		}
+		tried[b] = true
+		lastErr = err
+
+		wait := p.backoff(n)
+		p.logger.Warn("Retrying Ollama request",
+			logger.String("model", model),
+			logger.String("backend", b.url),
+			logger.Int("attempt", n),
+			logger.String("backoff", wait.String()),
+			logger.Error(err),
+		)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// backoff returns the delay before retry n, doubling from the initial backoff up
+// to the maximum and randomized over its upper half so retries do not align
+func (p *Pool) backoff(n int) time.Duration {
+	wait := p.maxBackoff
+	if n < 32 && p.initialBackoff<<(n-1) < wait {
+		wait = p.initialBackoff << (n - 1)
+	}
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// retryable reports whether a failed attempt may succeed when repeated:
+// connection failures, server errors and streams failing before their first response
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr *ollama.StatusError
+	if stderrors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+
+	var startErr *streamStartError
+	return isConnectionError(err) || stderrors.As(err, &startErr)
+}
@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/logger"
+)
+
+type contextKey string
+
+const sessionContextKey contextKey = "session"
+
+// session pins a conversation to the backend holding its KV cache
+type session struct {
+	backend  *backend
+	turn     string // Hash of the messages of the last recorded turn
+	context  int    // Tokens of that turn's conversation the backend has evaluated, a lower bound
+	lastUsed time.Time
+}
+
+// WithSession returns a context naming the session a chat request belongs to,
+// such as a client-provided session ID or the OpenAI user field
+func WithSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionContextKey, id)
+}
+
+// sessionFromContext retrieves the session named with WithSession
+func sessionFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(sessionContextKey).(string)
+	return id
+}
+
+// sessionKey identifies the conversation of a chat request for sticky routing,
+// or returns "" when sticky sessions are disabled
+// Without an explicit session the conversation is identified by its prefix up
+// to the first user message, which every follow-up turn repeats
+func (p *Pool) sessionKey(ctx context.Context, req *ollama.ChatRequest) string {
+	if !p.sticky {
+		return ""
+	}
+
+	h := sha256.New()
+	h.Write([]byte(ollama.NormalizeModelName(req.Model)))
+	h.Write([]byte{0})
+	if id := sessionFromContext(ctx); id != "" {
+		h.Write([]byte("session:" + id))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	enc := json.NewEncoder(h)
+	for _, msg := range req.Messages {
+		enc.Encode(msg)
+		if msg.Role == "user" {
+			return hex.EncodeToString(h.Sum(nil))
+		}
+	}
+	// A conversation without a user message has no follow-up turns to pin
+	return ""
+}
+
+// turnHashes identify a chat request within its conversation: current hashes its
+// messages, and previous the messages of the request before it, which end before
+// the last assistant message
+// Conversations sharing a session key only share cache accounting when one
+// really continues the other
+func (p *Pool) turnHashes(req *ollama.ChatRequest) (previous, current string) {
+	if !p.sticky {
+		return "", ""
+	}
+
+	last := -1
+	for i, msg := range req.Messages {
+		if msg.Role == "assistant" {
+			last = i
+		}
+	}
+
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for i, msg := range req.Messages {
+		if i == last {
+			previous = hex.EncodeToString(h.Sum(nil))
+		}
+		enc.Encode(msg)
+	}
+	return previous, hex.EncodeToString(h.Sum(nil))
+}
+
+// pinned returns the backend a session is pinned to when it can still serve
+// the model; p.mu must be held
+func (p *Pool) pinned(key, model string, exclude map[*backend]bool) *backend {
+	s := p.sessions[key]
+	if s == nil {
+		return nil
+	}
+	b := s.backend
+	if b.state != circuitClosed || exclude[b] || (model != "" && !b.models[ollama.NormalizeModelName(model)]) {
+		return nil
+	}
+	s.lastUsed = time.Now()
+	return b
+}
+
+// pin pins a session to the backend chosen for it, forgetting what the previous
+// backend had cached; p.mu must be held
+func (p *Pool) pin(key string, b *backend) {
+	if s := p.sessions[key]; s != nil && s.backend == b {
+		s.lastUsed = time.Now()
+		return
+	}
+	p.sessions[key] = &session{backend: b, lastUsed: time.Now()}
+}
+
+// recordTurn estimates how much of a session's prompt the backend served from
+// its KV cache: when it evaluated fewer prompt tokens than the conversation held
+// after the previous turn, that whole conversation was reused
+func (p *Pool) recordTurn(key, previous, current string, b *backend, evaluated, generated int) {
+	if key == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.sessions[key]
+	if s == nil || s.backend != b {
+		return
+	}
+
+	// A turn that does not continue the last recorded one starts from nothing cached
+	held := 0
+	if previous != "" && s.turn == previous {
+		held = s.context
+	}
+
+	b.sessionTurns++
+	cached := 0
+	if held > evaluated {
+		cached = held
+		b.cacheHits++
+		b.cachedTokens += int64(cached)
+	}
+	s.turn = current
+	s.context = cached + evaluated + generated
+
+	p.logger.Debug("Sticky session turn",
+		logger.String("backend", b.url),
+		logger.Int("prompt_eval_count", evaluated),
+		logger.Int("cached_tokens_estimate", cached),
+	)
+}
+
+// expireSessions unpins the sessions idle for longer than the session TTL
+func (p *Pool) expireSessions() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, s := range p.sessions {
+		if time.Since(s.lastUsed) > p.sessionTTL {
+			delete(p.sessions, key)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"ollama2openai/config"
+	"ollama2openai/pkg/errors"
+)
+
+// AzureHandler serves Azure OpenAI deployment routes by setting the request
+// model from the deployment and passing it to the matching OpenAI handler
+// Path format: /openai/deployments/{deployment}/{operation}
+func AzureHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, endpoints map[string]http.HandlerFunc) {
+	parts := splitPath(r.URL.Path)
+	if len(parts) < 4 {
+		writeError(w, errors.ErrNotFound)
+		return
+	}
+
+	deployment := parts[2]
+	endpoint := "/v1/" + strings.Join(parts[3:], "/")
+	handler, ok := endpoints[endpoint]
+	if !ok {
+		writeError(w, errors.ErrNotFound.WithMessage(fmt.Sprintf("Operation '%s' is not supported", strings.Join(parts[3:], "/"))))
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(w, errors.ErrMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, errors.ErrInvalidRequest.WithMessage("Failed to read request body"))
+		return
+	}
+
+	// Azure requests name the deployment in the path rather than a model in the body
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+	if fields == nil {
+		writeError(w, errors.ErrInvalidRequest.WithMessage("Request body must be a JSON object"))
+		return
+	}
+	fields["model"], _ = json.Marshal(cfg.GetDeploymentModel(deployment))
+	body, _ = json.Marshal(fields)
+
+	req := r.Clone(r.Context())
+	req.URL.Path = endpoint
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	handler(w, req)
+}
@@ -0,0 +1,40 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"ollama2openai/config"
+	"ollama2openai/pkg/errors"
+	"ollama2openai/provider"
+)
+
+// BackendsHandler reports the circuit state, load, models and sticky sessions of each Ollama backend
+func BackendsHandler(w http.ResponseWriter, r *http.Request, backends *provider.Pool) {
+	if r.Method != http.MethodGet {
+		writeError(w, errors.ErrMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"object": "list",
+		"data":   backends.Status(),
+	})
+}
+
+// withSession names the sticky session of a request after the configured session
+// header, or else after the client's own identifier such as the OpenAI user field
+// Sessions are scoped to the API key alias, so tenants choosing the same
+// identifier do not share a session
+func withSession(ctx context.Context, r *http.Request, cfg *config.Config, user string) context.Context {
+	id := r.Header.Get(cfg.LoadBalancing.SessionHeader)
+	if id == "" {
+		id = user
+	}
+	if id == "" {
+		return ctx
+	}
+	return provider.WithSession(ctx, getAliasFromRequest(r, cfg)+"\x00"+id)
+}
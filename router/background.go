@@ -0,0 +1,354 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ollama2openai/middleware"
+	"ollama2openai/ollama"
+	"ollama2openai/openai"
+	"ollama2openai/store"
+)
+
+// backgroundRetention is how long finished tasks keep their events in memory
+// After that, streaming a response replays it from the response store
+const backgroundRetention = 10 * time.Minute
+
+// BackgroundResponses tracks Response API requests running with background: true
+type BackgroundResponses struct {
+	mu    sync.Mutex
+	tasks map[string]*backgroundTask
+}
+
+// NewBackgroundResponses creates an empty background task registry
+func NewBackgroundResponses() *BackgroundResponses {
+	return &BackgroundResponses{
+		tasks: make(map[string]*backgroundTask),
+	}
+}
+
+type backgroundTask struct {
+	mu        sync.Mutex
+	cancel    context.CancelFunc
+	cancelled bool
+	events    []openai.ResponseStreamEvent
+	update    chan struct{} // Closed and replaced whenever an event is published
+	done      chan struct{} // Closed when the worker has saved the final state
+}
+
+func (b *BackgroundResponses) start(id string, cancel context.CancelFunc) *backgroundTask {
+	task := &backgroundTask{
+		cancel: cancel,
+		update: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	b.tasks[id] = task
+	b.mu.Unlock()
+
+	return task
+}
+
+func (b *BackgroundResponses) get(id string) *backgroundTask {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tasks[id]
+}
+
+// finish marks a task as done and schedules its removal
+func (b *BackgroundResponses) finish(id string, task *backgroundTask) {
+	task.mu.Lock()
+	close(task.done)
+	close(task.update)
+	task.mu.Unlock()
+
+	time.AfterFunc(backgroundRetention, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.tasks[id] == task {
+			delete(b.tasks, id)
+		}
+	})
+}
+
+// publish appends an event, assigning the next sequence number
+func (t *backgroundTask) publish(event openai.ResponseStreamEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	event.SequenceNumber = len(t.events)
+	t.events = append(t.events, event)
+
+	close(t.update)
+	t.update = make(chan struct{})
+}
+
+// eventsAfter returns events with a sequence number above after, a channel signalling
+// new events, and whether the task has finished
+func (t *backgroundTask) eventsAfter(after int) ([]openai.ResponseStreamEvent, <-chan struct{}, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var events []openai.ResponseStreamEvent
+	if after+1 < len(t.events) {
+		events = t.events[max(after+1, 0):]
+	}
+
+	select {
+	case <-t.done:
+		return events, nil, true
+	default:
+		return events, t.update, false
+	}
+}
+
+// requestCancel aborts the Ollama call, returning false if the task already finished
+func (t *backgroundTask) requestCancel() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	select {
+	case <-t.done:
+		return false
+	default:
+	}
+
+	t.cancelled = true
+	t.cancel()
+	return true
+}
+
+func (t *backgroundTask) wasCancelled() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cancelled
+}
+
+// runBackgroundResponse generates a queued response, publishing stream events
+// and saving each status change to the response store
+func runBackgroundResponse(ctx context.Context, b *BackgroundResponses, task *backgroundTask, client ollama.ClientInterface, usage middleware.UsageTracker, responses store.ResponseStore, stored *store.StoredResponse, chatReq *openai.ChatCompletionRequest, ollamaReq *ollama.ChatRequest) {
+	defer b.finish(stored.Response.ID, task)
+	defer task.cancel()
+
+	conversation := stored.Messages
+	response := stored.Response
+
+	save := func(eventType string) {
+		stored.Response = response
+		responses.Save(stored)
+		snapshot := response
+		task.publish(openai.ResponseStreamEvent{Type: eventType, Response: &snapshot})
+	}
+
+	response.Status = "in_progress"
+	save("response.in_progress")
+
+	ollamaReq.Stream = true
+	stream, err := client.ChatStream(ctx, ollamaReq)
+	if err != nil {
+		finishBackgroundResponse(task, &response, ctx, err)
+		save("response." + response.Status)
+		return
+	}
+	defer stream.Close()
+	response.Model = servedModel(response.Model, ollamaReq, stream.Model())
+
+	// Output items from before generation, such as file_search calls, come first
+	itemID := fmt.Sprintf("msg_%s", generateID())
+	outputIndex := len(response.Output)
+
+	assistant, err := readResponseStream(ctx, stream, itemID, outputIndex, task.publish)
+	if err != nil {
+		finishBackgroundResponse(task, &response, ctx, err)
+		save("response." + response.Status)
+		return
+	}
+
+	completeResponse(&response, chatReq, assistant, itemID, outputIndex)
+	usage.RecordCompletion(stored.Alias, response.Model, int64(response.Usage.PromptTokens), int64(response.Usage.CompletionTokens))
+	stored.Messages = appendAssistantMessage(conversation, assistant)
+	save("response.completed")
+}
+
+// completeResponse adds the generated message to a response's output under the
+// item ID its delta events used, and sets its usage and status
+func completeResponse(response *openai.ResponseResponse, chatReq *openai.ChatCompletionRequest, assistant openai.ChatMessage, itemID string, outputIndex int) {
+	promptTokens := estimatePromptTokens(chatReq)
+	completionTokens := estimateAssistantTokens(assistant)
+
+	response.Status = "completed"
+	response.Output = append(response.Output, buildOutputItems(assistant)...)
+	if len(response.Output) > outputIndex && response.Output[outputIndex].Type == "message" {
+		response.Output[outputIndex].ID = itemID
+	}
+	response.Usage = openai.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+}
+
+// readResponseStream reads a chat stream to its end, publishing each piece of
+// text as a response.output_text.delta event, and returns the generated message
+// A stream that fails or is cancelled part way returns an error
+func readResponseStream(ctx context.Context, stream *ollama.ChatStream, itemID string, outputIndex int, publish func(openai.ResponseStreamEvent)) (openai.ChatMessage, error) {
+	contentIndex := 0
+	var content strings.Builder
+	var toolCalls []ollama.ToolCall
+
+	for {
+		resp, err := stream.ReadResponse()
+		if err != nil {
+			if ctx.Err() != nil {
+				return openai.ChatMessage{}, ctx.Err()
+			}
+			if !stderrors.Is(err, io.EOF) {
+				return openai.ChatMessage{}, err
+			}
+			break
+		}
+
+		if resp.Message.Content != "" {
+			content.WriteString(resp.Message.Content)
+			publish(openai.ResponseStreamEvent{
+				Type:         "response.output_text.delta",
+				ItemID:       itemID,
+				OutputIndex:  &outputIndex,
+				ContentIndex: &contentIndex,
+				Delta:        resp.Message.Content,
+			})
+		}
+		toolCalls = append(toolCalls, resp.Message.ToolCalls...)
+
+		if resp.Done {
+			break
+		}
+	}
+
+	if ctx.Err() != nil {
+		return openai.ChatMessage{}, ctx.Err()
+	}
+	return openai.ChatMessage{
+		Role:      "assistant",
+		Content:   content.String(),
+		ToolCalls: convertToolCalls(toolCalls),
+	}, nil
+}
+
+// finishBackgroundResponse sets the final status for a response that did not complete
+func finishBackgroundResponse(task *backgroundTask, response *openai.ResponseResponse, ctx context.Context, err error) {
+	if task.wasCancelled() {
+		response.Status = "cancelled"
+		return
+	}
+	failResponse(response, ctx, err)
+}
+
+// failResponse marks a response as failed with the error that stopped it
+func failResponse(response *openai.ResponseResponse, ctx context.Context, err error) {
+	response.Status = "failed"
+	code := "server_error"
+	if ctx.Err() == context.DeadlineExceeded {
+		code = "request_timeout"
+	}
+	response.Error = &openai.ErrorDetail{
+		Message: fmt.Sprintf("Ollama error: %v", err),
+		Type:    "server_error",
+		Code:    code,
+	}
+}
+
+// streamResponseEvents writes a response's events as server-sent events, starting
+// after the given sequence number and following the task until it finishes
+func streamResponseEvents(w http.ResponseWriter, r *http.Request, task *backgroundTask, stored *store.StoredResponse, after int) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	flusher, _ := w.(http.Flusher)
+
+	if task == nil {
+		for _, event := range replayResponseEvents(stored) {
+			if event.SequenceNumber > after {
+				writeResponseEvent(w, event)
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return
+	}
+
+	for {
+		events, update, done := task.eventsAfter(after)
+		for _, event := range events {
+			writeResponseEvent(w, event)
+			after = event.SequenceNumber
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done {
+			return
+		}
+
+		select {
+		case <-update:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// replayResponseEvents rebuilds the event sequence of a finished response from the store
+func replayResponseEvents(stored *store.StoredResponse) []openai.ResponseStreamEvent {
+	response := stored.Response
+	created := response
+	created.Status = "queued"
+	created.Output = []openai.OutputItem{}
+	created.Error = nil
+
+	events := []openai.ResponseStreamEvent{
+		{Type: "response.created", Response: &created},
+	}
+
+	if response.Status == "queued" || response.Status == "in_progress" {
+		events = append(events, openai.ResponseStreamEvent{Type: "response." + response.Status, Response: &response})
+	} else {
+		for i, item := range response.Output {
+			outputIndex := i
+			for j, part := range item.Content {
+				contentIndex := j
+				if part.Text == "" {
+					continue
+				}
+				events = append(events, openai.ResponseStreamEvent{
+					Type:         "response.output_text.delta",
+					ItemID:       item.ID,
+					OutputIndex:  &outputIndex,
+					ContentIndex: &contentIndex,
+					Delta:        part.Text,
+				})
+			}
+		}
+		events = append(events, openai.ResponseStreamEvent{Type: "response." + response.Status, Response: &response})
+	}
+
+	for i := range events {
+		events[i].SequenceNumber = i
+	}
+	return events
+}
+
+func writeResponseEvent(w http.ResponseWriter, event openai.ResponseStreamEvent) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+}
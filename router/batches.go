@@ -0,0 +1,664 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"ollama2openai/batch"
+	"ollama2openai/config"
+	"ollama2openai/filestore"
+	"ollama2openai/middleware"
+	"ollama2openai/openai"
+	"ollama2openai/pkg/errors"
+	"ollama2openai/pkg/logger"
+)
+
+// batchCompletionWindow is the only completion window accepted for batches
+const batchCompletionWindow = "24h"
+
+// BatchRunner executes batch jobs line by line on a bounded pool of workers
+// Lines go through the same handlers as interactive requests, but only while
+// no interactive request is running
+type BatchRunner struct {
+	config   *config.Config
+	store    batch.Store
+	files    filestore.Storage
+	priority *Priority
+	handlers map[string]http.HandlerFunc // Endpoint -> handler
+	logger   logger.Logger
+	tasks    chan batchTask
+
+	mu      sync.Mutex
+	running map[string]*batchRun
+}
+
+// batchRun is the state of a batch being executed
+type batchRun struct {
+	ctx    context.Context // Cancelled by the cancel endpoint, expires with the batch
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	job     *batch.Job
+	output  *os.File // In-progress results, uploaded as files when the batch finishes
+	errFile *os.File
+}
+
+type batchTask struct {
+	run  *batchRun
+	line openai.BatchInputLine
+}
+
+// NewBatchRunner starts the worker pool and resumes batches left unfinished by a restart
+func NewBatchRunner(cfg *config.Config, store batch.Store, files filestore.Storage, priority *Priority, handlers map[string]http.HandlerFunc, log logger.Logger) *BatchRunner {
+	r := &BatchRunner{
+		config:   cfg,
+		store:    store,
+		files:    files,
+		priority: priority,
+		handlers: handlers,
+		logger:   log,
+		tasks:    make(chan batchTask),
+		running:  make(map[string]*batchRun),
+	}
+
+	for i := 0; i < cfg.Batches.Workers; i++ {
+		go r.worker()
+	}
+
+	jobs, err := store.List()
+	if err != nil {
+		log.Error("Failed to load batches", logger.Error(err))
+		return r
+	}
+	for _, job := range jobs {
+		switch job.Batch.Status {
+		case "validating", "in_progress", "finalizing", "cancelling":
+			log.Info("Resuming batch", logger.String("batch_id", job.Batch.ID), logger.String("status", job.Batch.Status))
+			r.start(job)
+		}
+	}
+	return r
+}
+
+// Submit saves a new batch and starts executing it
+func (r *BatchRunner) Submit(job *batch.Job) error {
+	if err := r.store.Save(job); err != nil {
+		return err
+	}
+	// The runner updates its own copy so the caller can still read job
+	running := *job
+	r.start(&running)
+	return nil
+}
+
+// Cancel stops a running batch, returning its state after the request
+func (r *BatchRunner) Cancel(job *batch.Job) (*batch.Job, *errors.APIError) {
+	r.mu.Lock()
+	run := r.running[job.Batch.ID]
+	r.mu.Unlock()
+
+	if run == nil {
+		return nil, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Batch '%s' cannot be cancelled in status '%s'", job.Batch.ID, job.Batch.Status))
+	}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	switch run.job.Batch.Status {
+	case "validating", "in_progress":
+		run.job.Batch.Status = "cancelling"
+		run.job.Batch.CancellingAt = unixNow()
+		r.store.Save(run.job)
+		run.cancel()
+	case "cancelling":
+	default:
+		return nil, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Batch '%s' cannot be cancelled in status '%s'", job.Batch.ID, run.job.Batch.Status))
+	}
+
+	snapshot := *run.job
+	return &snapshot, nil
+}
+
+func (r *BatchRunner) start(job *batch.Job) {
+	expires := time.Now().Add(24 * time.Hour)
+	if job.Batch.ExpiresAt != nil {
+		expires = time.Unix(*job.Batch.ExpiresAt, 0)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), expires)
+	if job.Batch.Status == "cancelling" {
+		cancel()
+	}
+
+	run := &batchRun{ctx: ctx, cancel: cancel, job: job}
+
+	r.mu.Lock()
+	r.running[job.Batch.ID] = run
+	r.mu.Unlock()
+
+	go r.process(run)
+}
+
+// process validates the input file, feeds its lines to the workers and finalizes the batch
+func (r *BatchRunner) process(run *batchRun) {
+	defer func() {
+		run.cancel()
+		r.mu.Lock()
+		delete(r.running, run.job.Batch.ID)
+		r.mu.Unlock()
+	}()
+
+	lines, batchErrors := r.readInput(run.job)
+	if batchErrors != nil {
+		run.mu.Lock()
+		run.job.Batch.Status = "failed"
+		run.job.Batch.FailedAt = unixNow()
+		run.job.Batch.Errors = batchErrors
+		r.store.Save(run.job)
+		run.mu.Unlock()
+		return
+	}
+
+	done, err := r.openResults(run)
+	if err != nil {
+		run.mu.Lock()
+		run.job.Batch.Status = "failed"
+		run.job.Batch.FailedAt = unixNow()
+		run.job.Batch.Errors = &openai.BatchErrors{
+			Object: "list",
+			Data:   []openai.BatchError{{Code: "internal_error", Message: err.Error()}},
+		}
+		r.store.Save(run.job)
+		run.mu.Unlock()
+		return
+	}
+
+	run.mu.Lock()
+	if run.job.Batch.Status == "validating" {
+		run.job.Batch.Status = "in_progress"
+		run.job.Batch.InProgressAt = unixNow()
+	}
+	run.job.Batch.RequestCounts.Total = len(lines)
+	r.store.Save(run.job)
+	run.mu.Unlock()
+
+feed:
+	for _, line := range lines {
+		if done[line.CustomID] {
+			continue
+		}
+		run.wg.Add(1)
+		select {
+		case r.tasks <- batchTask{run: run, line: line}:
+		case <-run.ctx.Done():
+			run.wg.Done()
+			break feed
+		}
+	}
+	run.wg.Wait()
+
+	r.finalize(run)
+}
+
+// readInput loads and validates the batch input file
+func (r *BatchRunner) readInput(job *batch.Job) ([]openai.BatchInputLine, *openai.BatchErrors) {
+	fail := func(code, message string, line int) *openai.BatchErrors {
+		e := openai.BatchError{Code: code, Message: message}
+		if line > 0 {
+			e.Line = &line
+		}
+		return &openai.BatchErrors{Object: "list", Data: []openai.BatchError{e}}
+	}
+
+	content, err := r.files.Open(job.Batch.InputFileID)
+	if err != nil {
+		return nil, fail("invalid_file", fmt.Sprintf("Input file '%s' could not be opened: %v", job.Batch.InputFileID, err), 0)
+	}
+	defer content.Close()
+
+	var lines []openai.BatchInputLine
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(content)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	n := 0
+	for scanner.Scan() {
+		n++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var line openai.BatchInputLine
+		if err := json.Unmarshal(text, &line); err != nil {
+			return nil, fail("invalid_json_line", "This line is not parseable as valid JSON", n)
+		}
+		switch {
+		case line.CustomID == "":
+			return nil, fail("missing_required_parameter", "custom_id is required", n)
+		case seen[line.CustomID]:
+			return nil, fail("duplicate_custom_id", fmt.Sprintf("custom_id '%s' is used more than once", line.CustomID), n)
+		case line.Method != http.MethodPost:
+			return nil, fail("invalid_method", "Only POST requests are supported", n)
+		case line.URL != job.Batch.Endpoint:
+			return nil, fail("mismatched_endpoint", fmt.Sprintf("The url '%s' does not match the batch endpoint '%s'", line.URL, job.Batch.Endpoint), n)
+		case len(line.Body) == 0 || line.Body[0] != '{':
+			return nil, fail("invalid_body", "body must be a JSON object", n)
+		}
+		seen[line.CustomID] = true
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fail("invalid_file", fmt.Sprintf("Failed to read input file: %v", err), 0)
+	}
+	if len(lines) == 0 {
+		return nil, fail("empty_file", "The input file contains no requests", 0)
+	}
+	return lines, nil
+}
+
+// openResults opens the in-progress result files, returning the custom IDs
+// already answered before a restart so they are not run again
+func (r *BatchRunner) openResults(run *batchRun) (map[string]bool, error) {
+	done := make(map[string]bool)
+	counts := openai.BatchRequestCounts{}
+
+	open := func(suffix string, count *int) (*os.File, error) {
+		path := filepath.Join(r.config.Batches.Path, run.job.Batch.ID+suffix)
+		if data, err := os.ReadFile(path); err == nil {
+			for _, raw := range bytes.Split(data, []byte("\n")) {
+				var line openai.BatchOutputLine
+				if json.Unmarshal(raw, &line) == nil && line.CustomID != "" {
+					done[line.CustomID] = true
+					*count++
+				}
+			}
+		}
+		return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	}
+
+	output, err := open(".output.jsonl", &counts.Completed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch output: %w", err)
+	}
+	errFile, err := open(".errors.jsonl", &counts.Failed)
+	if err != nil {
+		output.Close()
+		return nil, fmt.Errorf("failed to open batch errors: %w", err)
+	}
+
+	run.mu.Lock()
+	run.output = output
+	run.errFile = errFile
+	run.job.Batch.RequestCounts.Completed = counts.Completed
+	run.job.Batch.RequestCounts.Failed = counts.Failed
+	run.mu.Unlock()
+
+	return done, nil
+}
+
+func (r *BatchRunner) worker() {
+	for task := range r.tasks {
+		r.execute(task)
+		task.run.wg.Done()
+	}
+}
+
+// execute runs one batch line through its endpoint's handler once the priority gate allows it
+func (r *BatchRunner) execute(task batchTask) {
+	run := task.run
+	release, err := r.priority.Acquire(run.ctx)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	// Lines that cannot be sent are recorded as failed with the reason
+	fail := func(code, message string) {
+		r.record(run, openai.BatchOutputLine{
+			ID:       fmt.Sprintf("batch_req_%s", generateID()),
+			CustomID: task.line.CustomID,
+			Error:    &openai.BatchLineError{Code: code, Message: message},
+		}, false)
+	}
+
+	// Batch results are collected whole, so streaming is turned off
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(task.line.Body, &fields); err != nil {
+		fail("invalid_request", fmt.Sprintf("The request body is not a valid JSON object: %v", err))
+		return
+	}
+	delete(fields, "stream")
+	body, _ := json.Marshal(fields)
+
+	ctx := middleware.WithAlias(run.ctx, run.job.Alias)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, task.line.URL, bytes.NewReader(body))
+	if err != nil {
+		fail("invalid_request", fmt.Sprintf("The request could not be built: %v", err))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rec := newBufferedResponse()
+
+	r.handlers[task.line.URL](rec, req)
+
+	// Requests aborted by cancellation or expiry are left out of the results
+	if run.ctx.Err() != nil {
+		return
+	}
+
+	respBody := bytes.TrimSpace(rec.body.Bytes())
+	if !json.Valid(respBody) {
+		respBody, _ = json.Marshal(string(respBody))
+	}
+
+	id := generateID()
+	r.record(run, openai.BatchOutputLine{
+		ID:       fmt.Sprintf("batch_req_%s", id),
+		CustomID: task.line.CustomID,
+		Response: &openai.BatchLineResponse{
+			StatusCode: rec.status,
+			RequestID:  fmt.Sprintf("req_%s", id),
+			Body:       respBody,
+		},
+	}, rec.status < http.StatusMultipleChoices)
+}
+
+// record appends a result to the output or error file and saves the new counts
+func (r *BatchRunner) record(run *batchRun, line openai.BatchOutputLine, succeeded bool) {
+	data, _ := json.Marshal(line)
+	data = append(data, '\n')
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	file := run.errFile
+	if succeeded {
+		file = run.output
+	}
+	if _, err := file.Write(data); err != nil {
+		r.logger.Error("Failed to write batch result", logger.String("batch_id", run.job.Batch.ID), logger.Error(err))
+		return
+	}
+
+	if succeeded {
+		run.job.Batch.RequestCounts.Completed++
+	} else {
+		run.job.Batch.RequestCounts.Failed++
+	}
+	r.store.Save(run.job)
+}
+
+// finalize uploads the result files and sets the final status
+func (r *BatchRunner) finalize(run *batchRun) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	job := &run.job.Batch
+	cancelled := job.Status == "cancelling"
+	expired := !cancelled && stderrors.Is(run.ctx.Err(), context.DeadlineExceeded)
+
+	if !cancelled {
+		job.Status = "finalizing"
+		job.FinalizingAt = unixNow()
+		r.store.Save(run.job)
+	}
+
+	upload := func(f *os.File, name string) *string {
+		f.Close()
+		defer os.Remove(f.Name())
+
+		info, err := os.Stat(f.Name())
+		if err != nil || info.Size() == 0 {
+			return nil
+		}
+		content, err := os.Open(f.Name())
+		if err != nil {
+			return nil
+		}
+		defer content.Close()
+
+		file := &filestore.File{
+			ID:        fmt.Sprintf("file-%s", generateID()),
+			Alias:     run.job.Alias,
+			Filename:  name,
+			Purpose:   "batch_output",
+			CreatedAt: time.Now(),
+		}
+		if err := r.files.Save(file, content); err != nil {
+			r.logger.Error("Failed to store batch results", logger.String("batch_id", job.ID), logger.Error(err))
+			return nil
+		}
+		return &file.ID
+	}
+	job.OutputFileID = upload(run.output, job.ID+"_output.jsonl")
+	job.ErrorFileID = upload(run.errFile, job.ID+"_error.jsonl")
+
+	switch {
+	case cancelled:
+		job.Status = "cancelled"
+		job.CancelledAt = unixNow()
+	case expired:
+		job.Status = "expired"
+		job.ExpiredAt = unixNow()
+	default:
+		job.Status = "completed"
+		job.CompletedAt = unixNow()
+	}
+	r.store.Save(run.job)
+
+	r.logger.Info("Batch finished",
+		logger.String("batch_id", job.ID),
+		logger.String("status", job.Status),
+		logger.Int("completed", job.RequestCounts.Completed),
+		logger.Int("failed", job.RequestCounts.Failed),
+	)
+}
+
+// BatchHandler handles the batch API
+// Path format: /v1/batches, /v1/batches/{id} or /v1/batches/{id}/cancel
+func BatchHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, files filestore.Storage, runner *BatchRunner) {
+	parts := splitPath(r.URL.Path)
+	alias := getAliasFromRequest(r, cfg)
+
+	if len(parts) == 2 {
+		switch r.Method {
+		case http.MethodPost:
+			createBatch(w, r, files, runner, alias)
+		case http.MethodGet:
+			listBatches(w, r, runner, alias)
+		default:
+			writeError(w, errors.ErrMethodNotAllowed)
+		}
+		return
+	}
+
+	if len(parts) > 4 || (len(parts) == 4 && parts[3] != "cancel") {
+		writeError(w, errors.ErrNotFound)
+		return
+	}
+
+	job, apiErr := getBatch(runner.store, parts[2], alias)
+
+	if len(parts) == 4 {
+		if r.Method != http.MethodPost {
+			writeError(w, errors.ErrMethodNotAllowed)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+		job, apiErr = runner.Cancel(job)
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job.Batch)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, errors.ErrMethodNotAllowed)
+		return
+	}
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job.Batch)
+}
+
+func createBatch(w http.ResponseWriter, r *http.Request, files filestore.Storage, runner *BatchRunner, alias string) {
+	var req openai.BatchRequest
+	if apiErr := decodeRequestBody(r, &req); apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	if _, ok := runner.handlers[req.Endpoint]; !ok {
+		endpoints := make([]string, 0, len(runner.handlers))
+		for endpoint := range runner.handlers {
+			endpoints = append(endpoints, endpoint)
+		}
+		slices.Sort(endpoints)
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Unsupported endpoint '%s', expected one of: %v", req.Endpoint, endpoints)))
+		return
+	}
+	if req.CompletionWindow != batchCompletionWindow {
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("completion_window must be '%s'", batchCompletionWindow)))
+		return
+	}
+
+	input, apiErr := getUploadedFile(files, req.InputFileID, alias)
+	if apiErr != nil {
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Input file '%s' not found", req.InputFileID)))
+		return
+	}
+	if input.Purpose != "batch" {
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Input file '%s' must have purpose 'batch'", req.InputFileID)))
+		return
+	}
+
+	now := time.Now()
+	expires := now.Add(24 * time.Hour).Unix()
+	job := &batch.Job{
+		Alias: alias,
+		Batch: openai.Batch{
+			ID:               fmt.Sprintf("batch_%s", generateID()),
+			Object:           "batch",
+			Endpoint:         req.Endpoint,
+			InputFileID:      input.ID,
+			CompletionWindow: req.CompletionWindow,
+			Status:           "validating",
+			CreatedAt:        now.Unix(),
+			ExpiresAt:        &expires,
+			Metadata:         req.Metadata,
+		},
+	}
+
+	if err := runner.Submit(job); err != nil {
+		writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to create batch: %v", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job.Batch)
+}
+
+func listBatches(w http.ResponseWriter, r *http.Request, runner *BatchRunner, alias string) {
+	jobs, err := runner.store.List()
+	if err != nil {
+		writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to list batches: %v", err)))
+		return
+	}
+
+	var owned []*batch.Job
+	for _, job := range jobs {
+		if job.Alias == alias {
+			owned = append(owned, job)
+		}
+	}
+
+	page, hasMore, apiErr := paginate(r, owned, func(job *batch.Job) string { return job.Batch.ID })
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	list := openai.BatchList{
+		Object:  "list",
+		Data:    make([]openai.Batch, 0, len(page)),
+		HasMore: hasMore,
+	}
+	for _, job := range page {
+		list.Data = append(list.Data, job.Batch)
+	}
+	if len(list.Data) > 0 {
+		list.FirstID = list.Data[0].ID
+		list.LastID = list.Data[len(list.Data)-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// getBatch loads a batch, hiding batches created by other aliases
+func getBatch(store batch.Store, id, alias string) (*batch.Job, *errors.APIError) {
+	job, err := store.Get(id)
+	if err != nil {
+		if stderrors.Is(err, batch.ErrNotFound) {
+			return nil, errors.ErrNotFound.WithMessage(fmt.Sprintf("Batch '%s' not found", id))
+		}
+		return nil, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to load batch: %v", err))
+	}
+
+	if job.Alias != alias {
+		return nil, errors.ErrNotFound.WithMessage(fmt.Sprintf("Batch '%s' not found", id))
+	}
+
+	return job, nil
+}
+
+func unixNow() *int64 {
+	now := time.Now().Unix()
+	return &now
+}
+
+// bufferedResponse collects a handler's response in memory
+type bufferedResponse struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+}
+
+// Header returns the response headers
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+// Write buffers part of the response body
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
+
+// WriteHeader records the status code
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
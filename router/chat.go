@@ -3,6 +3,7 @@ package router
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -20,6 +21,19 @@ import (
 
 const defaultModel = "llama3"
 
+// servedModelHeader names the model that served a chat request, which is a
+// fallback model when the requested one failed
+const servedModelHeader = "X-Served-Model"
+
+// servedModel returns the model to report for a chat request: the requested
+// name, or the fallback model the provider reports serving ollamaReq instead
+func servedModel(requested string, ollamaReq *ollama.ChatRequest, served string) string {
+	if served == "" || served == ollamaReq.Model {
+		return requested
+	}
+	return served
+}
+
 // ChatHandler handles chat completion requests
 func ChatHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, client ollama.ClientInterface, usage middleware.UsageTracker) {
 	if r.Method != http.MethodPost {
@@ -52,7 +66,7 @@ func ChatHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, cli
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), cfg.GetTimeout())
+	ctx, cancel := context.WithTimeout(withSession(r.Context(), r, cfg, req.User), cfg.GetTimeout())
 	defer cancel()
 
 	// Get alias for usage tracking
@@ -69,11 +83,13 @@ func ChatHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, cli
 func handleStreamingChat(ctx context.Context, w http.ResponseWriter, cfg *config.Config, client ollama.ClientInterface, req *openai.ChatCompletionRequest, ollamaReq *ollama.ChatRequest, alias string, usage middleware.UsageTracker) {
 	stream, err := client.ChatStream(ctx, ollamaReq)
 	if err != nil {
-		writeError(w, errors.ErrOllamaConnection.WithMessage(fmt.Sprintf("Failed to start streaming: %v", err)))
+		writeError(w, errors.FromUpstream(err, fmt.Sprintf("Failed to start streaming: %v", err)))
 		return
 	}
 	defer stream.Close()
 
+	model := servedModel(req.Model, ollamaReq, stream.Model())
+	w.Header().Set(servedModelHeader, model)
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
@@ -84,10 +100,15 @@ func handleStreamingChat(ctx context.Context, w http.ResponseWriter, cfg *config
 
 	// Accumulate all content for token counting
 	var fullContent strings.Builder
+	var toolCalls []openai.ToolCall
+	var streamErr error
 
 	for {
 		resp, err := stream.ReadResponse()
 		if err != nil {
+			if !stderrors.Is(err, io.EOF) {
+				streamErr = err
+			}
 			break
 		}
 
@@ -97,7 +118,11 @@ func handleStreamingChat(ctx context.Context, w http.ResponseWriter, cfg *config
 		}
 
 		// Convert Ollama response to OpenAI format
-		chunk := convertToStreamChunk(&resp, req.Model, chunkID, created)
+		chunk := convertToStreamChunk(&resp, model, chunkID, created, len(toolCalls))
+		for _, tc := range chunk.Choices[0].Delta.ToolCalls {
+			tc.Index = nil
+			toolCalls = append(toolCalls, tc)
+		}
 
 		// Send SSE format
 		data, _ := json.Marshal(chunk)
@@ -111,16 +136,35 @@ func handleStreamingChat(ctx context.Context, w http.ResponseWriter, cfg *config
 	// Record usage once at the end with accumulated content
 	promptTokens := estimatePromptTokens(req)
 	completionTokens := tokenizer.EstimateTokenCount(fullContent.String())
-	usage.RecordCompletion(alias, int64(promptTokens), int64(completionTokens))
+	usage.RecordCompletion(alias, model, int64(promptTokens), int64(completionTokens))
+
+	if streamErr != nil {
+		writeStreamError(w, streamErr)
+		return
+	}
 
 	// Send [DONE]
 	fmt.Fprintf(w, "data: [DONE]\n\n")
 }
 
+// writeStreamError ends a stream cut short with an error event in place of [DONE],
+// so clients do not take the partial output for a complete one
+func writeStreamError(w http.ResponseWriter, err error) {
+	apiErr := errors.FromUpstream(err, fmt.Sprintf("Stream interrupted: %v", err))
+	data, _ := json.Marshal(errors.ErrorResponse{
+		Error: &errors.ErrorDetail{
+			Message: apiErr.Message,
+			Type:    apiErr.Type,
+			Code:    apiErr.Code,
+		},
+	})
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
 func handleNonStreamingChat(ctx context.Context, w http.ResponseWriter, cfg *config.Config, client ollama.ClientInterface, req *openai.ChatCompletionRequest, ollamaReq *ollama.ChatRequest, alias string, usage middleware.UsageTracker) {
 	resp, err := client.Chat(ctx, ollamaReq)
 	if err != nil {
-		writeError(w, errors.ErrOllamaConnection.WithMessage(fmt.Sprintf("Ollama error: %v", err)))
+		writeError(w, errors.FromUpstream(err, fmt.Sprintf("Ollama error: %v", err)))
 		return
 	}
 
@@ -130,12 +174,14 @@ func handleNonStreamingChat(ctx context.Context, w http.ResponseWriter, cfg *con
 	// Calculate completion tokens
 	completionTokens := tokenizer.EstimateTokenCount(resp.Message.Content)
 
-	// Record usage
-	usage.RecordCompletion(alias, int64(promptTokens), int64(completionTokens))
+	// Record usage against the model that served the request
+	model := servedModel(req.Model, ollamaReq, resp.Model)
+	usage.RecordCompletion(alias, model, int64(promptTokens), int64(completionTokens))
 
 	// Convert to OpenAI response format
-	openaiResp := convertToChatResponse(resp, req.Model, promptTokens, completionTokens)
+	openaiResp := convertToChatResponse(resp, model, promptTokens, completionTokens)
 
+	w.Header().Set(servedModelHeader, model)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(openaiResp)
 }
@@ -146,12 +192,40 @@ func convertChatRequest(req *openai.ChatCompletionRequest) (*ollama.ChatRequest,
 		Stream: req.Stream,
 	}
 
+	// Forward function tools unless the caller disabled tool use
+	if choice, _ := req.ToolChoice.(string); choice != "none" {
+		ollamaReq.Tools = convertTools(req.Tools)
+	}
+
+	// Ollama identifies tool results by function name rather than call ID
+	toolNames := make(map[string]string)
+
 	// Convert messages
 	for _, msg := range req.Messages {
 		ollamaMsg := ollama.ChatMessage{
 			Role: msg.Role,
 		}
 
+		for _, tc := range msg.ToolCalls {
+			if tc.Function == nil {
+				continue
+			}
+			args, err := parseToolArguments(tc.Function.Arguments)
+			if err != nil {
+				return nil, fmt.Errorf("invalid arguments for tool call %s: %w", tc.ID, err)
+			}
+			toolNames[tc.ID] = tc.Function.Name
+			ollamaMsg.ToolCalls = append(ollamaMsg.ToolCalls, ollama.ToolCall{
+				Function: ollama.ToolCallFunction{
+					Name:      tc.Function.Name,
+					Arguments: args,
+				},
+			})
+		}
+		if msg.Role == "tool" {
+			ollamaMsg.ToolName = toolNames[msg.ToolCallID]
+		}
+
 		// Handle content
 		switch c := msg.Content.(type) {
 		case string:
@@ -187,6 +261,65 @@ func convertChatRequest(req *openai.ChatCompletionRequest) (*ollama.ChatRequest,
 	return ollamaReq, nil
 }
 
+// convertTools converts OpenAI function tools to Ollama format
+func convertTools(tools []openai.Tool) []ollama.Tool {
+	var result []ollama.Tool
+	for _, tool := range tools {
+		if tool.Type != "function" || tool.Function == nil {
+			continue
+		}
+		result = append(result, ollama.Tool{
+			Type: "function",
+			Function: ollama.ToolFunction{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  tool.Function.Parameters,
+			},
+		})
+	}
+	return result
+}
+
+// convertToolCalls converts Ollama tool calls to OpenAI format, assigning call IDs
+func convertToolCalls(calls []ollama.ToolCall) []openai.ToolCall {
+	result := make([]openai.ToolCall, 0, len(calls))
+	for _, call := range calls {
+		result = append(result, openai.ToolCall{
+			ID:   fmt.Sprintf("call_%s", generateID()),
+			Type: "function",
+			Function: &openai.ToolCallFunction{
+				Name:      call.Function.Name,
+				Arguments: formatToolArguments(call.Function.Arguments),
+			},
+		})
+	}
+	return result
+}
+
+// parseToolArguments decodes OpenAI's JSON string arguments into Ollama's object form
+func parseToolArguments(arguments string) (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	if strings.TrimSpace(arguments) == "" {
+		return args, nil
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// formatToolArguments encodes Ollama's object arguments as OpenAI's JSON string
+func formatToolArguments(arguments map[string]interface{}) string {
+	if arguments == nil {
+		return "{}"
+	}
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
 type contentBuilder struct {
 	text   string
 	images []string
@@ -234,6 +367,12 @@ func convertToChatResponse(resp *ollama.ChatResponse, model string, promptTokens
 		finishReason = "stop"
 	}
 
+	var toolCalls []openai.ToolCall
+	if len(resp.Message.ToolCalls) > 0 {
+		toolCalls = convertToolCalls(resp.Message.ToolCalls)
+		finishReason = "tool_calls"
+	}
+
 	return openai.ChatCompletionResponse{
 		ID:      fmt.Sprintf("chatcmpl-%s", generateID()),
 		Object:  "chat.completion",
@@ -243,8 +382,9 @@ func convertToChatResponse(resp *ollama.ChatResponse, model string, promptTokens
 			{
 				Index: 0,
 				Message: openai.ChatMessage{
-					Role:    "assistant",
-					Content: resp.Message.Content,
+					Role:      "assistant",
+					Content:   resp.Message.Content,
+					ToolCalls: toolCalls,
 				},
 				FinishReason: finishReason,
 			},
@@ -257,10 +397,21 @@ func convertToChatResponse(resp *ollama.ChatResponse, model string, promptTokens
 	}
 }
 
-func convertToStreamChunk(resp *ollama.ChatResponse, model, chunkID string, created int64) openai.StreamChunk {
+// convertToStreamChunk converts a streamed Ollama response to an OpenAI chunk
+// toolIndex is the number of tool calls already sent in earlier chunks
+func convertToStreamChunk(resp *ollama.ChatResponse, model, chunkID string, created int64, toolIndex int) openai.StreamChunk {
 	finishReason := ""
 	if resp.Done {
 		finishReason = "stop"
+		if toolIndex > 0 || len(resp.Message.ToolCalls) > 0 {
+			finishReason = "tool_calls"
+		}
+	}
+
+	toolCalls := convertToolCalls(resp.Message.ToolCalls)
+	for i := range toolCalls {
+		index := toolIndex + i
+		toolCalls[i].Index = &index
 	}
 
 	return openai.StreamChunk{
@@ -272,8 +423,9 @@ func convertToStreamChunk(resp *ollama.ChatResponse, model, chunkID string, crea
 			{
 				Index: 0,
 				Delta: openai.ChatMessage{
-					Role:    resp.Message.Role,
-					Content: resp.Message.Content,
+					Role:      resp.Message.Role,
+					Content:   resp.Message.Content,
+					ToolCalls: toolCalls,
 				},
 				FinishReason: finishReason,
 			},
@@ -0,0 +1,279 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ollama2openai/config"
+	"ollama2openai/middleware"
+	"ollama2openai/openai"
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/errors"
+	"ollama2openai/tokenizer"
+)
+
+// CompletionHandler handles legacy text completion requests using Ollama's generate API
+func CompletionHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, client ollama.ClientInterface, usage middleware.UsageTracker, vocabs *tokenizer.Registry) {
+	if r.Method != http.MethodPost {
+		writeError(w, errors.ErrMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, errors.ErrInvalidRequest.WithMessage("Failed to read request body"))
+		return
+	}
+
+	var req openai.CompletionRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+
+	// Use default model if not specified
+	if req.Model == "" {
+		req.Model = defaultModel
+	}
+
+	// Handle string, string array and token array prompts
+	prompts, apiErr := parseTextInputs(req.Prompt, "prompt", req.Model, vocabs)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), cfg.GetTimeout())
+	defer cancel()
+
+	alias := getAliasFromRequest(r, cfg)
+	options := completionOptions(&req)
+
+	response := openai.CompletionResponse{
+		ID:      fmt.Sprintf("cmpl-%s", generateID()),
+		Object:  "text_completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+	}
+	requests := make([]*ollama.GenerateRequest, len(prompts))
+	for i, prompt := range prompts {
+		requests[i] = &ollama.GenerateRequest{
+			Model:   req.Model,
+			Prompt:  prompt,
+			Options: options,
+		}
+	}
+
+	if req.Stream {
+		handleStreamingCompletion(ctx, w, client, response, requests, alias, usage)
+		return
+	}
+
+	// Ollama generates one prompt per request, so the prompts are sent concurrently
+	results := make([]*ollama.GenerateResponse, len(requests))
+	errs := make([]error, len(requests))
+	var wg sync.WaitGroup
+	for i, genReq := range requests {
+		wg.Add(1)
+		go func(i int, genReq *ollama.GenerateRequest) {
+			defer wg.Done()
+			results[i], errs[i] = client.Generate(ctx, genReq)
+		}(i, genReq)
+	}
+	wg.Wait()
+
+	totalPrompt, totalCompletion := 0, 0
+	for i, resp := range results {
+		if errs[i] != nil {
+			writeError(w, errors.FromUpstream(errs[i], fmt.Sprintf("Ollama error: %v", errs[i])))
+			return
+		}
+
+		promptTokens, completionTokens := generateUsage(requests[i], resp.PromptEvalCount, resp.EvalCount, resp.Response)
+		totalPrompt += promptTokens
+		totalCompletion += completionTokens
+
+		response.Choices = append(response.Choices, openai.CompletionChoice{
+			Text:         resp.Response,
+			Index:        i,
+			FinishReason: completionFinishReason(resp.DoneReason),
+		})
+	}
+
+	usage.RecordCompletion(alias, req.Model, int64(totalPrompt), int64(totalCompletion))
+
+	response.Usage = &openai.Usage{
+		PromptTokens:     totalPrompt,
+		CompletionTokens: totalCompletion,
+		TotalTokens:      totalPrompt + totalCompletion,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleStreamingCompletion streams the completions of all prompts at once,
+// each chunk carrying one choice identified by the index of its prompt
+// Every stream is opened before the response starts, so a failing prompt
+// fails the whole request with a proper error
+func handleStreamingCompletion(ctx context.Context, w http.ResponseWriter, client ollama.ClientInterface, response openai.CompletionResponse, requests []*ollama.GenerateRequest, alias string, usage middleware.UsageTracker) {
+	streams := make([]*ollama.GenerateStream, len(requests))
+	errs := make([]error, len(requests))
+	var wg sync.WaitGroup
+	for i, genReq := range requests {
+		wg.Add(1)
+		go func(i int, genReq *ollama.GenerateRequest) {
+			defer wg.Done()
+			genReq.Stream = true
+			streams[i], errs[i] = client.GenerateStream(ctx, genReq)
+		}(i, genReq)
+	}
+	wg.Wait()
+
+	for _, stream := range streams {
+		if stream != nil {
+			defer stream.Close()
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			writeError(w, errors.FromUpstream(err, fmt.Sprintf("Failed to start streaming: %v", err)))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	type indexedResponse struct {
+		index int
+		resp  ollama.GenerateResponse
+	}
+	responses := make(chan indexedResponse)
+	streamErrs := make([]error, len(streams))
+	for i, stream := range streams {
+		wg.Add(1)
+		go func(i int, stream *ollama.GenerateStream) {
+			defer wg.Done()
+			for {
+				resp, err := stream.ReadResponse()
+				if err != nil {
+					if !stderrors.Is(err, io.EOF) {
+						streamErrs[i] = err
+					}
+					return
+				}
+				select {
+				case responses <- indexedResponse{index: i, resp: resp}:
+				case <-ctx.Done():
+					return
+				}
+				if resp.Done {
+					return
+				}
+			}
+		}(i, stream)
+	}
+	go func() {
+		wg.Wait()
+		close(responses)
+	}()
+
+	flusher, _ := w.(http.Flusher)
+	generated := make([]strings.Builder, len(requests))
+	finished := make([]bool, len(requests))
+	totalPrompt, totalCompletion := 0, 0
+	for r := range responses {
+		generated[r.index].WriteString(r.resp.Response)
+
+		choice := openai.CompletionChoice{Text: r.resp.Response, Index: r.index}
+		if r.resp.Done {
+			choice.FinishReason = completionFinishReason(r.resp.DoneReason)
+			promptTokens, completionTokens := generateUsage(requests[r.index], r.resp.PromptEvalCount, r.resp.EvalCount, generated[r.index].String())
+			totalPrompt += promptTokens
+			totalCompletion += completionTokens
+			finished[r.index] = true
+		}
+
+		chunk := response
+		chunk.Choices = []openai.CompletionChoice{choice}
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	// Choices cut short are estimated from what they generated
+	for i, done := range finished {
+		if !done {
+			promptTokens, completionTokens := generateUsage(requests[i], 0, 0, generated[i].String())
+			totalPrompt += promptTokens
+			totalCompletion += completionTokens
+		}
+	}
+	usage.RecordCompletion(alias, response.Model, int64(totalPrompt), int64(totalCompletion))
+
+	for _, err := range streamErrs {
+		if err != nil {
+			writeStreamError(w, err)
+			return
+		}
+	}
+	fmt.Fprintf(w, "data: [DONE]\n\n")
+}
+
+// generateUsage returns the prompt and completion tokens of a generation,
+// estimating the counts Ollama did not report
+func generateUsage(req *ollama.GenerateRequest, promptTokens, completionTokens int, text string) (int, int) {
+	if promptTokens == 0 {
+		promptTokens = tokenizer.EstimateTokenCount(req.Prompt)
+	}
+	if completionTokens == 0 {
+		completionTokens = tokenizer.EstimateTokenCount(text)
+	}
+	return promptTokens, completionTokens
+}
+
+// completionFinishReason maps Ollama's done reason to an OpenAI finish reason
+func completionFinishReason(doneReason string) string {
+	if doneReason == "length" {
+		return "length"
+	}
+	return "stop"
+}
+
+// completionOptions maps completion sampling parameters to Ollama options
+func completionOptions(req *openai.CompletionRequest) map[string]interface{} {
+	options := make(map[string]interface{})
+
+	if req.Temperature != nil {
+		options["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		options["top_p"] = *req.TopP
+	}
+	if req.MaxTokens != nil {
+		options["num_predict"] = *req.MaxTokens
+	}
+
+	switch stop := req.Stop.(type) {
+	case string:
+		options["stop"] = []string{stop}
+	case []interface{}:
+		options["stop"] = stop
+	}
+
+	if len(options) == 0 {
+		return nil
+	}
+	return options
+}
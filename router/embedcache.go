@@ -0,0 +1,159 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"ollama2openai/cache"
+	"ollama2openai/config"
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/errors"
+	"ollama2openai/tokenizer"
+)
+
+// ModelDigests tracks the digest of each local model, refreshed from Ollama's tag list
+// so cached embeddings can be invalidated when a model is re-pulled
+type ModelDigests struct {
+	client   ollama.ClientInterface
+	refresh  time.Duration
+	onChange func(model, digest string)
+
+	mu      sync.Mutex
+	digests map[string]string
+	fetched time.Time
+}
+
+// NewModelDigests creates a digest tracker calling onChange when a known model's digest changes
+func NewModelDigests(client ollama.ClientInterface, refresh time.Duration, onChange func(model, digest string)) *ModelDigests {
+	return &ModelDigests{
+		client:   client,
+		refresh:  refresh,
+		onChange: onChange,
+		digests:  make(map[string]string),
+	}
+}
+
+// Digest returns the current digest for model, or an empty string if the model is unknown
+// Stale digests are refreshed by the first caller noticing; others meanwhile use
+// the previous digests instead of waiting for Ollama
+func (d *ModelDigests) Digest(ctx context.Context, model string) string {
+	d.mu.Lock()
+	stale := time.Since(d.fetched) >= d.refresh
+	if stale {
+		d.fetched = time.Now()
+	}
+	d.mu.Unlock()
+
+	if stale {
+		d.update(ctx)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.digests[ollama.NormalizeModelName(model)]
+}
+
+// update reloads digests from Ollama, keeping the previous ones if the call fails
+// The tag list is fetched without holding d.mu
+func (d *ModelDigests) update(ctx context.Context) {
+	resp, err := d.client.Tags(ctx)
+	if err != nil {
+		return
+	}
+
+	digests := make(map[string]string, len(resp.Models))
+	for _, m := range resp.Models {
+		digests[ollama.NormalizeModelName(m.Name)] = m.Digest
+	}
+
+	d.mu.Lock()
+	previous := d.digests
+	d.digests = digests
+	d.mu.Unlock()
+
+	for name, digest := range digests {
+		if old, ok := previous[name]; ok && old != digest && d.onChange != nil {
+			d.onChange(name, digest)
+		}
+	}
+}
+
+// embedder embeds inputs through the optional cache, sending only misses to Ollama
+type embedder struct {
+	client    ollama.ClientInterface
+	batchSize int
+	cache     cache.EmbeddingCache // nil when caching is disabled
+	digests   *ModelDigests
+}
+
+// embed returns one embedding per input in input order and the prompt tokens used
+// Cached inputs are counted with the token estimate since Ollama did not see them
+func (e *embedder) embed(ctx context.Context, model string, inputs []string) ([][]float64, int, error) {
+	if e.cache == nil || e.digests == nil {
+		return embedInputs(ctx, e.client, model, inputs, e.batchSize)
+	}
+
+	// Without a digest there is no way to tell when entries go stale
+	digest := e.digests.Digest(ctx, model)
+	if digest == "" {
+		return embedInputs(ctx, e.client, model, inputs, e.batchSize)
+	}
+
+	name := ollama.NormalizeModelName(model)
+	embeddings := make([][]float64, len(inputs))
+	keys := make([]cache.Key, len(inputs))
+	totalTokens := 0
+
+	var missIndexes []int
+	var missInputs []string
+
+	for i, input := range inputs {
+		keys[i] = cache.NewKey(name, digest, input)
+		if emb, ok := e.cache.Get(keys[i]); ok {
+			embeddings[i] = emb
+			totalTokens += tokenizer.EstimateTokenCount(input)
+			continue
+		}
+		missIndexes = append(missIndexes, i)
+		missInputs = append(missInputs, input)
+	}
+
+	if len(missInputs) > 0 {
+		fresh, tokens, err := embedInputs(ctx, e.client, model, missInputs, e.batchSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		for j, i := range missIndexes {
+			embeddings[i] = fresh[j]
+			e.cache.Put(keys[i], fresh[j])
+		}
+		totalTokens += tokens
+	}
+
+	return embeddings, totalTokens, nil
+}
+
+// EmbeddingCacheHandler reports embedding cache hit metrics
+func EmbeddingCacheHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, embedCache cache.EmbeddingCache) {
+	if r.Method != http.MethodGet {
+		writeError(w, errors.ErrMethodNotAllowed)
+		return
+	}
+
+	// The statistics reveal which models are used and how much, so only admins see them
+	if !cfg.IsAdmin(getAliasFromRequest(r, cfg)) {
+		writeError(w, errors.ErrPermissionDenied.WithMessage("Cache statistics require an admin API key"))
+		return
+	}
+
+	result := map[string]interface{}{"enabled": embedCache != nil}
+	if embedCache != nil {
+		result["stats"] = embedCache.Stats()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -1,21 +1,24 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 
+	"ollama2openai/cache"
 	"ollama2openai/config"
 	"ollama2openai/middleware"
 	"ollama2openai/openai"
 	"ollama2openai/ollama"
 	"ollama2openai/pkg/errors"
+	"ollama2openai/pkg/vector"
 	"ollama2openai/tokenizer"
 )
 
 // EmbeddingHandler handles embedding requests
-func EmbeddingHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, client ollama.ClientInterface, usage middleware.UsageTracker) {
+func EmbeddingHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, client ollama.ClientInterface, usage middleware.UsageTracker, embedCache cache.EmbeddingCache, digests *ModelDigests, vocabs *tokenizer.Registry) {
 	if r.Method != http.MethodPost {
 		writeError(w, errors.ErrMethodNotAllowed)
 		return
@@ -39,35 +42,82 @@ func EmbeddingHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config
 		req.Model = "nomic-embed-text"
 	}
 
+	encodingFormat := "float"
+	if req.EncodingFormat != nil && *req.EncodingFormat != "" {
+		encodingFormat = *req.EncodingFormat
+	}
+	if encodingFormat != "float" && encodingFormat != "base64" {
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Unsupported encoding_format '%s', expected 'float' or 'base64'", encodingFormat)))
+		return
+	}
+
+	if req.Dimensions != nil {
+		if *req.Dimensions <= 0 {
+			writeError(w, errors.ErrInvalidRequest.WithMessage("dimensions must be a positive integer"))
+			return
+		}
+		if !cfg.SupportsDimensions(req.Model) {
+			writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Model '%s' does not support the dimensions parameter", req.Model)))
+			return
+		}
+	}
+
+	chunking := cfg.Embeddings.Chunking.Mode
+	if req.Chunking != "" {
+		chunking = req.Chunking
+	}
+	if chunking != "none" && chunking != "mean" && chunking != "chunks" {
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Unsupported chunking '%s', expected 'none', 'mean' or 'chunks'", chunking)))
+		return
+	}
+
 	alias := getAliasFromRequest(r, cfg)
 
-	// Handle both string and array inputs
-	inputs := parseEmbeddingInput(req.Input)
+	// Handle string, string array and token array inputs
+	inputs, apiErr := parseTextInputs(req.Input, "input", req.Model, vocabs)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
 
 	ctx := r.Context()
 
-	var embeddings [][]float64
-	totalTokens := 0
-
-	for _, input := range inputs {
-		ollamaReq := &ollama.EmbeddingRequest{
-			Model:  req.Model,
-			Input:  input,
-		}
+	e := &embedder{
+		client:    client,
+		batchSize: cfg.Embeddings.BatchSize,
+		cache:     embedCache,
+		digests:   digests,
+	}
 
-		resp, err := client.Embedding(ctx, ollamaReq)
-		if err != nil {
-			writeError(w, errors.ErrOllamaConnection.WithMessage(fmt.Sprintf("Ollama error: %v", err)))
-			return
-		}
+	var embeddings [][]float64
+	var chunks [][]chunkEmbedding
+	var totalTokens int
+
+	if chunking == "none" {
+		embeddings, totalTokens, err = e.embed(ctx, req.Model, inputs)
+	} else {
+		embeddings, chunks, totalTokens, err = embedChunked(ctx, e, req.Model, inputs,
+			cfg.GetEmbeddingContextLength(req.Model), cfg.Embeddings.Chunking.Overlap)
+	}
+	if err != nil {
+		writeError(w, errors.FromUpstream(err, fmt.Sprintf("Ollama error: %v", err)))
+		return
+	}
 
-		// Extract embeddings
-		for _, emb := range resp.Embeddings {
-			embeddings = append(embeddings, emb)
+	// Shorten embeddings for Matryoshka models, renormalizing to unit length
+	if req.Dimensions != nil {
+		for i, emb := range embeddings {
+			if *req.Dimensions > len(emb) {
+				writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("dimensions %d exceeds the model's embedding size of %d", *req.Dimensions, len(emb))))
+				return
+			}
+			embeddings[i] = vector.Truncate(emb, *req.Dimensions)
+			if chunks != nil {
+				for j := range chunks[i] {
+					chunks[i][j].embedding = vector.Truncate(chunks[i][j].embedding, *req.Dimensions)
+				}
+			}
 		}
-
-		// Estimate tokens for this input
-		totalTokens += tokenizer.EstimateTokenCount(input)
 	}
 
 	// Record usage
@@ -87,28 +137,109 @@ func EmbeddingHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config
 	for i, emb := range embeddings {
 		response.Data[i] = openai.EmbeddingData{
 			Object:    "embedding",
-			Embedding: emb,
+			Embedding: encodeEmbedding(emb, encodingFormat),
 			Index:     i,
 		}
+		if chunking == "chunks" {
+			for _, c := range chunks[i] {
+				response.Data[i].Chunks = append(response.Data[i].Chunks, openai.EmbeddingChunk{
+					Embedding: encodeEmbedding(c.embedding, encodingFormat),
+					Start:     c.window.Start,
+					End:       c.window.End,
+				})
+			}
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func parseEmbeddingInput(input interface{}) []string {
-	switch v := input.(type) {
-	case string:
-		return []string{v}
-	case []interface{}:
-		result := make([]string, 0, len(v))
-		for _, item := range v {
-			if s, ok := item.(string); ok {
-				result = append(result, s)
+// encodeEmbedding returns emb as a float array or, for "base64", as a base64 string
+func encodeEmbedding(emb []float64, encodingFormat string) interface{} {
+	if encodingFormat == "base64" {
+		return vector.EncodeBase64(emb)
+	}
+	return emb
+}
+
+// chunkEmbedding is the embedding of one window of an input
+type chunkEmbedding struct {
+	window    tokenizer.Window
+	embedding []float64
+}
+
+// embedChunked splits inputs longer than contextLength tokens into overlapping windows,
+// embeds every window and mean-pools each input's windows into one normalized embedding
+// The returned token count covers all windows
+func embedChunked(ctx context.Context, e *embedder, model string, inputs []string, contextLength, overlap int) ([][]float64, [][]chunkEmbedding, int, error) {
+	windows := make([][]tokenizer.Window, len(inputs))
+	var texts []string
+	for i, input := range inputs {
+		windows[i] = tokenizer.SplitWindows(input, contextLength, overlap)
+		for _, w := range windows[i] {
+			texts = append(texts, w.Text)
+		}
+	}
+
+	flat, totalTokens, err := e.embed(ctx, model, texts)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	pooled := make([][]float64, len(inputs))
+	chunks := make([][]chunkEmbedding, len(inputs))
+	n := 0
+	for i := range inputs {
+		group := make([][]float64, len(windows[i]))
+		for j, w := range windows[i] {
+			group[j] = flat[n]
+			chunks[i] = append(chunks[i], chunkEmbedding{window: w, embedding: flat[n]})
+			n++
+		}
+
+		if len(group) == 1 {
+			pooled[i] = group[0]
+		} else {
+			pooled[i] = vector.Normalize(vector.Mean(group))
+		}
+	}
+
+	return pooled, chunks, totalTokens, nil
+}
+
+// embedInputs embeds inputs in batches of at most batchSize, returning one embedding
+// per input in input order and the number of prompt tokens used
+func embedInputs(ctx context.Context, client ollama.ClientInterface, model string, inputs []string, batchSize int) ([][]float64, int, error) {
+	embeddings := make([][]float64, len(inputs))
+	totalTokens := 0
+
+	for start := 0; start < len(inputs); start += batchSize {
+		end := min(start+batchSize, len(inputs))
+		batch := inputs[start:end]
+
+		resp, err := client.Embedding(ctx, &ollama.EmbeddingRequest{
+			Model: model,
+			Input: batch,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(resp.Embeddings) != len(batch) {
+			return nil, 0, fmt.Errorf("expected %d embeddings, got %d", len(batch), len(resp.Embeddings))
+		}
+
+		copy(embeddings[start:end], resp.Embeddings)
+
+		// Prefer Ollama's own token count, estimating only if it was not reported
+		if resp.PromptEvalCount > 0 {
+			totalTokens += resp.PromptEvalCount
+		} else {
+			for _, input := range batch {
+				totalTokens += tokenizer.EstimateTokenCount(input)
 			}
 		}
-		return result
-	default:
-		return []string{}
 	}
+
+	return embeddings, totalTokens, nil
 }
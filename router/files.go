@@ -0,0 +1,253 @@
+package router
+
+import (
+	"bufio"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"ollama2openai/config"
+	"ollama2openai/filestore"
+	"ollama2openai/openai"
+	"ollama2openai/pkg/errors"
+)
+
+// filePurposes lists the accepted upload purposes
+var filePurposes = []string{"batch", "assistants", "user_data"}
+
+// FileHandler handles the files API
+// Path format: /v1/files, /v1/files/{id} or /v1/files/{id}/content
+func FileHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, files filestore.Storage) {
+	parts := splitPath(r.URL.Path)
+	alias := getAliasFromRequest(r, cfg)
+
+	if len(parts) == 2 {
+		switch r.Method {
+		case http.MethodPost:
+			uploadFile(w, r, cfg, files, alias)
+		case http.MethodGet:
+			listFiles(w, r, files, alias)
+		default:
+			writeError(w, errors.ErrMethodNotAllowed)
+		}
+		return
+	}
+
+	if len(parts) > 4 || (len(parts) == 4 && parts[3] != "content") {
+		writeError(w, errors.ErrNotFound)
+		return
+	}
+
+	file, apiErr := getUploadedFile(files, parts[2], alias)
+
+	if len(parts) == 4 {
+		if r.Method != http.MethodGet {
+			writeError(w, errors.ErrMethodNotAllowed)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+		content, err := files.Open(file.ID)
+		if err != nil {
+			writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to open file: %v", err)))
+			return
+		}
+		defer content.Close()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Filename))
+		io.Copy(w, content)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toOpenAIFile(file))
+	case http.MethodDelete:
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+		if err := files.Delete(file.ID); err != nil && !stderrors.Is(err, filestore.ErrNotFound) {
+			writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to delete file: %v", err)))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openai.DeletedObject{
+			ID:      file.ID,
+			Object:  "file",
+			Deleted: true,
+		})
+	default:
+		writeError(w, errors.ErrMethodNotAllowed)
+	}
+}
+
+// uploadFile stores a multipart upload with "file" and "purpose" fields
+func uploadFile(w http.ResponseWriter, r *http.Request, cfg *config.Config, files filestore.Storage, alias string) {
+	maxSize := cfg.GetMaxFileSize()
+
+	// Leave room for the multipart framing and the purpose field
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if stderrors.As(err, &tooLarge) {
+			writeError(w, errors.ErrFileTooLarge.WithMessage(fmt.Sprintf("File exceeds the maximum size of %d MB", cfg.Files.MaxSizeMB)))
+			return
+		}
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Invalid multipart form: %v", err)))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	purpose := r.FormValue("purpose")
+	if !slices.Contains(filePurposes, purpose) {
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Invalid purpose '%s', expected one of: %s", purpose, strings.Join(filePurposes, ", "))))
+		return
+	}
+
+	upload, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, errors.ErrInvalidRequest.WithMessage("'file' is required"))
+		return
+	}
+	defer upload.Close()
+
+	if header.Size > maxSize {
+		writeError(w, errors.ErrFileTooLarge.WithMessage(fmt.Sprintf("File exceeds the maximum size of %d MB", cfg.Files.MaxSizeMB)))
+		return
+	}
+
+	if purpose == "batch" {
+		if apiErr := validateJSONL(upload, header.Filename); apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+		if _, err := upload.Seek(0, io.SeekStart); err != nil {
+			writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to read upload: %v", err)))
+			return
+		}
+	}
+
+	file := &filestore.File{
+		ID:        fmt.Sprintf("file-%s", generateID()),
+		Alias:     alias,
+		Filename:  header.Filename,
+		Purpose:   purpose,
+		CreatedAt: time.Now(),
+	}
+	if err := files.Save(file, upload); err != nil {
+		writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to store file: %v", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toOpenAIFile(file))
+}
+
+// validateJSONL checks that a batch input has a .jsonl name and one JSON object per line
+func validateJSONL(content io.Reader, filename string) *errors.APIError {
+	if !strings.HasSuffix(filename, ".jsonl") {
+		return errors.ErrInvalidRequest.WithMessage("Batch input files must be .jsonl files")
+	}
+
+	scanner := bufio.NewScanner(content)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &obj); err != nil {
+			return errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Line %d of the batch input is not a JSON object", line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Failed to read batch input: %v", err))
+	}
+	return nil
+}
+
+func listFiles(w http.ResponseWriter, r *http.Request, files filestore.Storage, alias string) {
+	all, err := files.List(alias)
+	if err != nil {
+		writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to list files: %v", err)))
+		return
+	}
+
+	if purpose := r.URL.Query().Get("purpose"); purpose != "" {
+		filtered := all[:0]
+		for _, f := range all {
+			if f.Purpose == purpose {
+				filtered = append(filtered, f)
+			}
+		}
+		all = filtered
+	}
+
+	page, hasMore, apiErr := paginate(r, all, func(f *filestore.File) string { return f.ID })
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	list := openai.FileList{
+		Object:  "list",
+		Data:    make([]openai.FileObject, 0, len(page)),
+		HasMore: hasMore,
+	}
+	for _, f := range page {
+		list.Data = append(list.Data, toOpenAIFile(f))
+	}
+	if len(list.Data) > 0 {
+		list.FirstID = list.Data[0].ID
+		list.LastID = list.Data[len(list.Data)-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// getUploadedFile loads a file's metadata, hiding files owned by other aliases
+func getUploadedFile(files filestore.Storage, id, alias string) (*filestore.File, *errors.APIError) {
+	file, err := files.Get(id)
+	if err != nil {
+		if stderrors.Is(err, filestore.ErrNotFound) {
+			return nil, errors.ErrNotFound.WithMessage(fmt.Sprintf("File '%s' not found", id))
+		}
+		return nil, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to load file: %v", err))
+	}
+
+	if file.Alias != alias {
+		return nil, errors.ErrNotFound.WithMessage(fmt.Sprintf("File '%s' not found", id))
+	}
+
+	return file, nil
+}
+
+func toOpenAIFile(f *filestore.File) openai.FileObject {
+	return openai.FileObject{
+		ID:        f.ID,
+		Object:    "file",
+		Bytes:     f.Bytes,
+		CreatedAt: f.CreatedAt.Unix(),
+		Filename:  f.Filename,
+		Purpose:   f.Purpose,
+		Status:    "processed",
+	}
+}
@@ -0,0 +1,373 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"ollama2openai/config"
+	"ollama2openai/gemini"
+	"ollama2openai/middleware"
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/errors"
+	"ollama2openai/tokenizer"
+)
+
+// GeminiHandler handles Gemini generateContent and streamGenerateContent requests
+// Path format: /v1beta/models/{model}:{method}
+func GeminiHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, client ollama.ClientInterface, usage middleware.UsageTracker) {
+	if r.Method != http.MethodPost {
+		writeGeminiError(w, errors.ErrMethodNotAllowed)
+		return
+	}
+
+	// Ollama model names may contain a tag, so the method follows the last colon
+	name := strings.TrimPrefix(r.URL.Path, "/v1beta/models/")
+	sep := strings.LastIndex(name, ":")
+	if sep <= 0 {
+		writeGeminiError(w, errors.ErrNotFound)
+		return
+	}
+	model, method := name[:sep], name[sep+1:]
+	if method != "generateContent" && method != "streamGenerateContent" {
+		writeGeminiError(w, errors.ErrNotFound.WithMessage(fmt.Sprintf("Method '%s' is not supported", method)))
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeGeminiError(w, errors.ErrInvalidRequest.WithMessage("Failed to read request body"))
+		return
+	}
+
+	var req gemini.GenerateContentRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeGeminiError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+
+	if len(req.Contents) == 0 {
+		writeGeminiError(w, errors.ErrInvalidRequest.WithMessage("'contents' must not be empty"))
+		return
+	}
+
+	ollamaReq := convertGeminiRequest(model, &req)
+
+	ctx, cancel := context.WithTimeout(withSession(r.Context(), r, cfg, ""), cfg.GetTimeout())
+	defer cancel()
+
+	alias := getAliasFromRequest(r, cfg)
+
+	if method == "streamGenerateContent" {
+		handleStreamingGemini(ctx, w, client, model, r.URL.Query().Get("alt") == "sse", ollamaReq, alias, usage)
+		return
+	}
+
+	resp, err := client.Chat(ctx, ollamaReq)
+	if err != nil {
+		writeGeminiError(w, errors.FromUpstream(err, fmt.Sprintf("Ollama error: %v", err)))
+		return
+	}
+
+	promptTokens, completionTokens := resp.PromptEvalCount, resp.EvalCount
+	if promptTokens == 0 {
+		promptTokens = estimateOllamaPromptTokens(ollamaReq)
+	}
+	if completionTokens == 0 {
+		completionTokens = tokenizer.EstimateTokenCount(resp.Message.Content)
+	}
+	model = servedModel(model, ollamaReq, resp.Model)
+	usage.RecordCompletion(alias, model, int64(promptTokens), int64(completionTokens))
+
+	response := geminiChunk(model, resp)
+	response.Candidates[0].FinishReason = geminiFinishReason(resp.DoneReason)
+	response.UsageMetadata = &gemini.UsageMetadata{
+		PromptTokenCount:     promptTokens,
+		CandidatesTokenCount: completionTokens,
+		TotalTokenCount:      promptTokens + completionTokens,
+	}
+
+	w.Header().Set(servedModelHeader, model)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleStreamingGemini streams chunks as server-sent events with alt=sse,
+// or otherwise as the elements of a JSON array written as they arrive
+func handleStreamingGemini(ctx context.Context, w http.ResponseWriter, client ollama.ClientInterface, model string, sse bool, ollamaReq *ollama.ChatRequest, alias string, usage middleware.UsageTracker) {
+	ollamaReq.Stream = true
+	stream, err := client.ChatStream(ctx, ollamaReq)
+	if err != nil {
+		writeGeminiError(w, errors.FromUpstream(err, fmt.Sprintf("Failed to start streaming: %v", err)))
+		return
+	}
+	defer stream.Close()
+
+	model = servedModel(model, ollamaReq, stream.Model())
+	w.Header().Set(servedModelHeader, model)
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "[")
+	}
+
+	flusher, _ := w.(http.Flusher)
+	sent := 0
+	send := func(chunk interface{}) {
+		data, _ := json.Marshal(chunk)
+		switch {
+		case sse:
+			fmt.Fprintf(w, "data: %s\r\n\r\n", data)
+		case sent > 0:
+			fmt.Fprintf(w, ",\r\n%s", data)
+		default:
+			w.Write(data)
+		}
+		sent++
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	var fullContent strings.Builder
+	var streamErr error
+	done := false
+	for {
+		resp, err := stream.ReadResponse()
+		if err != nil {
+			if !stderrors.Is(err, io.EOF) {
+				streamErr = err
+			}
+			break
+		}
+		fullContent.WriteString(resp.Message.Content)
+
+		if !resp.Done {
+			if resp.Message.Content != "" || len(resp.Message.ToolCalls) > 0 {
+				send(geminiChunk(model, &resp))
+			}
+			continue
+		}
+
+		// The last chunk carries the finish reason and usage
+		promptTokens, completionTokens := resp.PromptEvalCount, resp.EvalCount
+		if promptTokens == 0 {
+			promptTokens = estimateOllamaPromptTokens(ollamaReq)
+		}
+		if completionTokens == 0 {
+			completionTokens = tokenizer.EstimateTokenCount(fullContent.String())
+		}
+		usage.RecordCompletion(alias, model, int64(promptTokens), int64(completionTokens))
+
+		chunk := geminiChunk(model, &resp)
+		chunk.Candidates[0].FinishReason = geminiFinishReason(resp.DoneReason)
+		chunk.UsageMetadata = &gemini.UsageMetadata{
+			PromptTokenCount:     promptTokens,
+			CandidatesTokenCount: completionTokens,
+			TotalTokenCount:      promptTokens + completionTokens,
+		}
+		send(chunk)
+		done = true
+		break
+	}
+
+	// A stream cut short records what was generated and ends with an error
+	if !done {
+		promptTokens := estimateOllamaPromptTokens(ollamaReq)
+		completionTokens := tokenizer.EstimateTokenCount(fullContent.String())
+		usage.RecordCompletion(alias, model, int64(promptTokens), int64(completionTokens))
+	}
+	if streamErr != nil {
+		send(geminiErrorResponse(errors.FromUpstream(streamErr, fmt.Sprintf("Stream interrupted: %v", streamErr))))
+	}
+
+	if !sse {
+		fmt.Fprint(w, "]")
+	}
+}
+
+// convertGeminiRequest converts a Gemini request to an Ollama chat request
+func convertGeminiRequest(model string, req *gemini.GenerateContentRequest) *ollama.ChatRequest {
+	ollamaReq := &ollama.ChatRequest{
+		Model: model,
+	}
+
+	if req.ToolConfig == nil || req.ToolConfig.FunctionCallingConfig == nil || req.ToolConfig.FunctionCallingConfig.Mode != "NONE" {
+		for _, tool := range req.Tools {
+			for _, fn := range tool.FunctionDeclarations {
+				ollamaReq.Tools = append(ollamaReq.Tools, ollama.Tool{
+					Type: "function",
+					Function: ollama.ToolFunction{
+						Name:        fn.Name,
+						Description: fn.Description,
+						Parameters:  fn.Parameters,
+					},
+				})
+			}
+		}
+	}
+
+	if req.SystemInstruction != nil {
+		if system := geminiPartsText(req.SystemInstruction.Parts); system != "" {
+			ollamaReq.Messages = append(ollamaReq.Messages, ollama.ChatMessage{Role: "system", Content: system})
+		}
+	}
+
+	for _, content := range req.Contents {
+		role := "user"
+		if content.Role == "model" {
+			role = "assistant"
+		}
+		msg := ollama.ChatMessage{Role: role}
+
+		for _, part := range content.Parts {
+			switch {
+			case part.InlineData != nil:
+				msg.Images = append(msg.Images, part.InlineData.Data)
+			case part.FunctionCall != nil:
+				msg.ToolCalls = append(msg.ToolCalls, ollama.ToolCall{
+					Function: ollama.ToolCallFunction{
+						Name:      part.FunctionCall.Name,
+						Arguments: part.FunctionCall.Args,
+					},
+				})
+			case part.FunctionResponse != nil:
+				// Function results become "tool" messages ahead of the rest of the turn
+				result, _ := json.Marshal(part.FunctionResponse.Response)
+				ollamaReq.Messages = append(ollamaReq.Messages, ollama.ChatMessage{
+					Role:     "tool",
+					Content:  string(result),
+					ToolName: part.FunctionResponse.Name,
+				})
+			default:
+				msg.Content += part.Text
+			}
+		}
+
+		if msg.Content != "" || len(msg.Images) > 0 || len(msg.ToolCalls) > 0 {
+			ollamaReq.Messages = append(ollamaReq.Messages, msg)
+		}
+	}
+
+	if gc := req.GenerationConfig; gc != nil {
+		options := make(map[string]interface{})
+		if gc.Temperature != nil {
+			options["temperature"] = *gc.Temperature
+		}
+		if gc.TopP != nil {
+			options["top_p"] = *gc.TopP
+		}
+		if gc.TopK != nil {
+			options["top_k"] = *gc.TopK
+		}
+		if gc.MaxOutputTokens != nil {
+			options["num_predict"] = *gc.MaxOutputTokens
+		}
+		if gc.Seed != nil {
+			options["seed"] = *gc.Seed
+		}
+		if len(gc.StopSequences) > 0 {
+			options["stop"] = gc.StopSequences
+		}
+		if len(options) > 0 {
+			ollamaReq.Options = options
+		}
+
+		if gc.ResponseMimeType == "application/json" {
+			ollamaReq.Format = "json"
+		}
+	}
+
+	return ollamaReq
+}
+
+// geminiPartsText joins the text parts of a content
+func geminiPartsText(parts []gemini.Part) string {
+	var text strings.Builder
+	for _, part := range parts {
+		text.WriteString(part.Text)
+	}
+	return text.String()
+}
+
+// geminiChunk wraps an Ollama message as a single-candidate Gemini response
+func geminiChunk(model string, resp *ollama.ChatResponse) gemini.GenerateContentResponse {
+	parts := []gemini.Part{}
+	if resp.Message.Content != "" {
+		parts = append(parts, gemini.Part{Text: resp.Message.Content})
+	}
+	for _, call := range resp.Message.ToolCalls {
+		args := call.Function.Arguments
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		parts = append(parts, gemini.Part{
+			FunctionCall: &gemini.FunctionCall{Name: call.Function.Name, Args: args},
+		})
+	}
+
+	return gemini.GenerateContentResponse{
+		Candidates: []gemini.Candidate{
+			{
+				Content: gemini.Content{Role: "model", Parts: parts},
+				Index:   0,
+			},
+		},
+		ModelVersion: model,
+	}
+}
+
+// geminiFinishReason maps Ollama's done reason to a Gemini finish reason
+func geminiFinishReason(doneReason string) string {
+	if doneReason == "length" {
+		return "MAX_TOKENS"
+	}
+	return "STOP"
+}
+
+// writeGeminiError writes an error in the Gemini error format
+func writeGeminiError(w http.ResponseWriter, err *errors.APIError) {
+	errors.SetRetryAfter(w, err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.StatusCode)
+	json.NewEncoder(w).Encode(geminiErrorResponse(err))
+}
+
+// geminiErrorResponse converts an error to the Gemini format, naming the RPC
+// status closest to its HTTP status
+func geminiErrorResponse(err *errors.APIError) gemini.ErrorResponse {
+	status := "INTERNAL"
+	switch err.StatusCode {
+	case http.StatusBadRequest:
+		status = "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		status = "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		status = "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		status = "NOT_FOUND"
+	case http.StatusMethodNotAllowed:
+		status = "UNIMPLEMENTED"
+	case http.StatusTooManyRequests:
+		status = "RESOURCE_EXHAUSTED"
+	case http.StatusServiceUnavailable:
+		status = "UNAVAILABLE"
+	case http.StatusGatewayTimeout:
+		status = "DEADLINE_EXCEEDED"
+	}
+
+	return gemini.ErrorResponse{
+		Error: gemini.ErrorDetail{
+			Code:    err.StatusCode,
+			Message: err.Message,
+			Status:  status,
+		},
+	}
+}
@@ -0,0 +1,91 @@
+package router
+
+import (
+	stderrors "errors"
+	"fmt"
+	"math"
+
+	"ollama2openai/pkg/errors"
+	"ollama2openai/tokenizer"
+)
+
+// parseTextInputs converts an OpenAI input or prompt field to a list of texts
+// The field may be a string, an array of strings, an array of token IDs or an
+// array of token ID arrays; token arrays are decoded with the model's vocabulary
+func parseTextInputs(input interface{}, field, model string, vocabs *tokenizer.Registry) ([]string, *errors.APIError) {
+	switch v := input.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("'%s' must not be empty", field))
+		}
+
+		switch v[0].(type) {
+		case string:
+			result := make([]string, 0, len(v))
+			for i, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return nil, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("'%s[%d]' must be a string", field, i))
+				}
+				result = append(result, s)
+			}
+			return result, nil
+		case float64:
+			text, apiErr := decodeTokenArray(v, field, model, vocabs)
+			if apiErr != nil {
+				return nil, apiErr
+			}
+			return []string{text}, nil
+		case []interface{}:
+			result := make([]string, 0, len(v))
+			for i, item := range v {
+				tokens, ok := item.([]interface{})
+				if !ok || len(tokens) == 0 {
+					return nil, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("'%s[%d]' must be a non-empty token array", field, i))
+				}
+				text, apiErr := decodeTokenArray(tokens, fmt.Sprintf("%s[%d]", field, i), model, vocabs)
+				if apiErr != nil {
+					return nil, apiErr
+				}
+				result = append(result, text)
+			}
+			return result, nil
+		}
+	case nil:
+		return nil, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("'%s' is required", field))
+	}
+
+	return nil, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("'%s' must be a string, an array of strings, a token array or an array of token arrays", field))
+}
+
+// decodeTokenArray decodes a JSON array of token IDs using the model's vocabulary
+func decodeTokenArray(items []interface{}, field, model string, vocabs *tokenizer.Registry) (string, *errors.APIError) {
+	ids := make([]int, 0, len(items))
+	for i, item := range items {
+		f, ok := item.(float64)
+		if !ok || f < 0 || f != math.Trunc(f) {
+			return "", errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("'%s[%d]' must be a non-negative integer token ID", field, i))
+		}
+		ids = append(ids, int(f))
+	}
+
+	if vocabs == nil {
+		return "", errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Token array input is not supported for model '%s': no tokenizer vocabulary is configured", model))
+	}
+
+	vocab, err := vocabs.Get(model)
+	if err != nil {
+		if stderrors.Is(err, tokenizer.ErrNoVocabulary) {
+			return "", errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Token array input is not supported for model '%s': no tokenizer vocabulary is configured", model))
+		}
+		return "", errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to load tokenizer for model '%s': %v", model, err))
+	}
+
+	text, err := vocab.Decode(ids)
+	if err != nil {
+		return "", errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Invalid '%s': %v", field, err))
+	}
+	return text, nil
+}
@@ -0,0 +1,409 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"ollama2openai/anthropic"
+	"ollama2openai/config"
+	"ollama2openai/middleware"
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/errors"
+	"ollama2openai/tokenizer"
+)
+
+// MessagesHandler handles Anthropic Messages API requests
+func MessagesHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, client ollama.ClientInterface, usage middleware.UsageTracker) {
+	if r.Method != http.MethodPost {
+		writeAnthropicError(w, errors.ErrMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeAnthropicError(w, errors.ErrInvalidRequest.WithMessage("Failed to read request body"))
+		return
+	}
+
+	var req anthropic.MessagesRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeAnthropicError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+
+	// Use default model if not specified
+	if req.Model == "" {
+		req.Model = defaultModel
+	}
+
+	if len(req.Messages) == 0 {
+		writeAnthropicError(w, errors.ErrInvalidRequest.WithMessage("'messages' must not be empty"))
+		return
+	}
+
+	ollamaReq, err := convertMessagesRequest(&req)
+	if err != nil {
+		writeAnthropicError(w, errors.ErrInvalidRequest.WithMessage(err.Error()))
+		return
+	}
+
+	userID, _ := req.Metadata["user_id"].(string)
+	ctx, cancel := context.WithTimeout(withSession(r.Context(), r, cfg, userID), cfg.GetTimeout())
+	defer cancel()
+
+	alias := getAliasFromRequest(r, cfg)
+
+	if req.Stream {
+		handleStreamingMessages(ctx, w, client, &req, ollamaReq, alias, usage)
+		return
+	}
+
+	resp, err := client.Chat(ctx, ollamaReq)
+	if err != nil {
+		writeAnthropicError(w, errors.FromUpstream(err, fmt.Sprintf("Ollama error: %v", err)))
+		return
+	}
+
+	inputTokens, outputTokens := resp.PromptEvalCount, resp.EvalCount
+	if inputTokens == 0 {
+		inputTokens = estimateOllamaPromptTokens(ollamaReq)
+	}
+	if outputTokens == 0 {
+		outputTokens = tokenizer.EstimateTokenCount(resp.Message.Content)
+	}
+	model := servedModel(req.Model, ollamaReq, resp.Model)
+	usage.RecordCompletion(alias, model, int64(inputTokens), int64(outputTokens))
+
+	content := make([]interface{}, 0, 1+len(resp.Message.ToolCalls))
+	if resp.Message.Content != "" {
+		content = append(content, anthropic.TextBlock{Type: "text", Text: resp.Message.Content})
+	}
+	for _, block := range convertToToolUseBlocks(resp.Message.ToolCalls) {
+		content = append(content, block)
+	}
+
+	stopReason := messagesStopReason(resp.DoneReason, len(resp.Message.ToolCalls) > 0)
+	response := anthropic.MessagesResponse{
+		ID:         fmt.Sprintf("msg_%s", generateID()),
+		Type:       "message",
+		Role:       "assistant",
+		Model:      model,
+		Content:    content,
+		StopReason: &stopReason,
+		Usage: anthropic.Usage{
+			InputTokens:  inputTokens,
+			OutputTokens: outputTokens,
+		},
+	}
+
+	w.Header().Set(servedModelHeader, model)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleStreamingMessages streams the reply as Anthropic's named SSE events
+func handleStreamingMessages(ctx context.Context, w http.ResponseWriter, client ollama.ClientInterface, req *anthropic.MessagesRequest, ollamaReq *ollama.ChatRequest, alias string, usage middleware.UsageTracker) {
+	ollamaReq.Stream = true
+	stream, err := client.ChatStream(ctx, ollamaReq)
+	if err != nil {
+		writeAnthropicError(w, errors.FromUpstream(err, fmt.Sprintf("Failed to start streaming: %v", err)))
+		return
+	}
+	defer stream.Close()
+
+	model := servedModel(req.Model, ollamaReq, stream.Model())
+	w.Header().Set(servedModelHeader, model)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	flusher, _ := w.(http.Flusher)
+	send := func(event anthropic.StreamEvent) {
+		data, _ := json.Marshal(event)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	inputTokens := estimateOllamaPromptTokens(ollamaReq)
+	send(anthropic.StreamEvent{
+		Type: "message_start",
+		Message: &anthropic.MessagesResponse{
+			ID:      fmt.Sprintf("msg_%s", generateID()),
+			Type:    "message",
+			Role:    "assistant",
+			Model:   model,
+			Content: []interface{}{},
+			Usage:   anthropic.Usage{InputTokens: inputTokens},
+		},
+	})
+
+	// index is the next content block; textOpen is set while a text block is open
+	index := 0
+	textOpen := false
+	closeText := func() {
+		if textOpen {
+			send(anthropic.StreamEvent{Type: "content_block_stop", Index: &index})
+			index++
+			textOpen = false
+		}
+	}
+
+	var fullContent strings.Builder
+	var final ollama.ChatResponse
+	var streamErr error
+	toolUse := false
+
+	for {
+		resp, err := stream.ReadResponse()
+		if err != nil {
+			if !stderrors.Is(err, io.EOF) {
+				streamErr = err
+			}
+			break
+		}
+
+		if resp.Message.Content != "" {
+			if !textOpen {
+				send(anthropic.StreamEvent{
+					Type:         "content_block_start",
+					Index:        &index,
+					ContentBlock: anthropic.TextBlock{Type: "text", Text: ""},
+				})
+				textOpen = true
+			}
+			send(anthropic.StreamEvent{
+				Type:  "content_block_delta",
+				Index: &index,
+				Delta: anthropic.TextDelta{Type: "text_delta", Text: resp.Message.Content},
+			})
+			fullContent.WriteString(resp.Message.Content)
+		}
+
+		// Ollama sends complete tool calls, so each is one block with a single input delta
+		for _, block := range convertToToolUseBlocks(resp.Message.ToolCalls) {
+			closeText()
+			input, _ := json.Marshal(block.Input)
+			block.Input = map[string]interface{}{}
+			send(anthropic.StreamEvent{Type: "content_block_start", Index: &index, ContentBlock: block})
+			send(anthropic.StreamEvent{
+				Type:  "content_block_delta",
+				Index: &index,
+				Delta: anthropic.InputJSONDelta{Type: "input_json_delta", PartialJSON: string(input)},
+			})
+			send(anthropic.StreamEvent{Type: "content_block_stop", Index: &index})
+			index++
+			toolUse = true
+		}
+
+		if resp.Done {
+			final = resp
+			break
+		}
+	}
+	closeText()
+
+	outputTokens := final.EvalCount
+	if outputTokens == 0 {
+		outputTokens = tokenizer.EstimateTokenCount(fullContent.String())
+	}
+	if final.PromptEvalCount > 0 {
+		inputTokens = final.PromptEvalCount
+	}
+	usage.RecordCompletion(alias, model, int64(inputTokens), int64(outputTokens))
+
+	// A stream cut short ends with an error event in place of message_stop
+	if streamErr != nil {
+		apiErr := errors.FromUpstream(streamErr, fmt.Sprintf("Stream interrupted: %v", streamErr))
+		send(anthropic.StreamEvent{
+			Type:  "error",
+			Error: &anthropic.ErrorDetail{Type: anthropicErrorType(apiErr), Message: apiErr.Message},
+		})
+		return
+	}
+
+	stopReason := messagesStopReason(final.DoneReason, toolUse)
+	send(anthropic.StreamEvent{
+		Type:  "message_delta",
+		Delta: anthropic.MessageDelta{StopReason: &stopReason},
+		Usage: &anthropic.Usage{InputTokens: inputTokens, OutputTokens: outputTokens},
+	})
+	send(anthropic.StreamEvent{Type: "message_stop"})
+}
+
+// convertMessagesRequest converts an Anthropic request to an Ollama chat request
+func convertMessagesRequest(req *anthropic.MessagesRequest) (*ollama.ChatRequest, error) {
+	ollamaReq := &ollama.ChatRequest{
+		Model: req.Model,
+	}
+
+	if req.ToolChoice == nil || req.ToolChoice.Type != "none" {
+		for _, tool := range req.Tools {
+			ollamaReq.Tools = append(ollamaReq.Tools, ollama.Tool{
+				Type: "function",
+				Function: ollama.ToolFunction{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  tool.InputSchema,
+				},
+			})
+		}
+	}
+
+	if system := contentText(req.System); system != "" {
+		ollamaReq.Messages = append(ollamaReq.Messages, ollama.ChatMessage{Role: "system", Content: system})
+	}
+
+	// Ollama identifies tool results by function name rather than tool_use ID
+	toolNames := make(map[string]string)
+
+	for i, msg := range req.Messages {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			return nil, fmt.Errorf("messages[%d]: unsupported role '%s'", i, msg.Role)
+		}
+
+		ollamaMsg := ollama.ChatMessage{Role: msg.Role}
+		var text strings.Builder
+
+		for _, block := range msg.Content {
+			switch block.Type {
+			case "text":
+				text.WriteString(block.Text)
+			case "image":
+				if block.Source == nil || block.Source.Type != "base64" {
+					return nil, fmt.Errorf("messages[%d]: only base64 image sources are supported", i)
+				}
+				ollamaMsg.Images = append(ollamaMsg.Images, block.Source.Data)
+			case "tool_use":
+				toolNames[block.ID] = block.Name
+				ollamaMsg.ToolCalls = append(ollamaMsg.ToolCalls, ollama.ToolCall{
+					Function: ollama.ToolCallFunction{
+						Name:      block.Name,
+						Arguments: block.Input,
+					},
+				})
+			case "tool_result":
+				// Tool results become "tool" messages ahead of the rest of the turn
+				result := contentText(block.Content)
+				if block.IsError {
+					result = "Error: " + result
+				}
+				ollamaReq.Messages = append(ollamaReq.Messages, ollama.ChatMessage{
+					Role:     "tool",
+					Content:  result,
+					ToolName: toolNames[block.ToolUseID],
+				})
+			}
+		}
+
+		ollamaMsg.Content = text.String()
+		if ollamaMsg.Content != "" || len(ollamaMsg.Images) > 0 || len(ollamaMsg.ToolCalls) > 0 {
+			ollamaReq.Messages = append(ollamaReq.Messages, ollamaMsg)
+		}
+	}
+
+	options := make(map[string]interface{})
+	if req.MaxTokens > 0 {
+		options["num_predict"] = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		options["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		options["top_p"] = *req.TopP
+	}
+	if req.TopK != nil {
+		options["top_k"] = *req.TopK
+	}
+	if len(req.StopSequences) > 0 {
+		options["stop"] = req.StopSequences
+	}
+	if len(options) > 0 {
+		ollamaReq.Options = options
+	}
+
+	return ollamaReq, nil
+}
+
+// contentText joins the text blocks of a content list
+func contentText(content anthropic.Content) string {
+	var text strings.Builder
+	for _, block := range content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return text.String()
+}
+
+// convertToToolUseBlocks converts Ollama tool calls to tool_use blocks, assigning IDs
+func convertToToolUseBlocks(calls []ollama.ToolCall) []anthropic.ToolUseBlock {
+	blocks := make([]anthropic.ToolUseBlock, 0, len(calls))
+	for _, call := range calls {
+		input := call.Function.Arguments
+		if input == nil {
+			input = map[string]interface{}{}
+		}
+		blocks = append(blocks, anthropic.ToolUseBlock{
+			Type:  "tool_use",
+			ID:    fmt.Sprintf("toolu_%s", generateID()),
+			Name:  call.Function.Name,
+			Input: input,
+		})
+	}
+	return blocks
+}
+
+// messagesStopReason maps Ollama's done reason to an Anthropic stop reason
+// Ollama does not say which stop sequence ended the reply, so those report end_turn
+func messagesStopReason(doneReason string, toolUse bool) string {
+	switch {
+	case toolUse:
+		return "tool_use"
+	case doneReason == "length":
+		return "max_tokens"
+	default:
+		return "end_turn"
+	}
+}
+
+// estimateOllamaPromptTokens estimates the prompt size when Ollama does not report it
+func estimateOllamaPromptTokens(req *ollama.ChatRequest) int {
+	messages := make([]map[string]interface{}, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = map[string]interface{}{
+			"role":    msg.Role,
+			"content": msg.Content,
+		}
+	}
+	return tokenizer.EstimateMessagesTokenCount(messages)
+}
+
+// writeAnthropicError writes an error in the Anthropic error format
+func writeAnthropicError(w http.ResponseWriter, err *errors.APIError) {
+	errors.SetRetryAfter(w, err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.StatusCode)
+	json.NewEncoder(w).Encode(anthropic.ErrorResponse{
+		Type: "error",
+		Error: anthropic.ErrorDetail{
+			Type:    anthropicErrorType(err),
+			Message: err.Message,
+		},
+	})
+}
+
+// anthropicErrorType maps an error's type to the closest Anthropic error type
+func anthropicErrorType(err *errors.APIError) string {
+	switch err.Type {
+	case errors.TypeServer, errors.TypeTimeout:
+		return "api_error"
+	}
+	return err.Type
+}
@@ -23,7 +23,7 @@ func ModelsHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, c
 
 	resp, err := client.Tags(ctx)
 	if err != nil {
-		writeError(w, errors.ErrOllamaConnection.WithMessage(fmt.Sprintf("Failed to get models: %v", err)))
+		writeError(w, errors.FromUpstream(err, fmt.Sprintf("Failed to get models: %v", err)))
 		return
 	}
 
@@ -70,7 +70,7 @@ func ModelHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, cl
 
 	resp, err := client.Tags(ctx)
 	if err != nil {
-		writeError(w, errors.ErrOllamaConnection.WithMessage(fmt.Sprintf("Failed to get models: %v", err)))
+		writeError(w, errors.FromUpstream(err, fmt.Sprintf("Failed to get models: %v", err)))
 		return
 	}
 
@@ -0,0 +1,194 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"ollama2openai/config"
+	"ollama2openai/middleware"
+	"ollama2openai/openai"
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/errors"
+)
+
+// moderationCategories lists the categories of an OpenAI moderation result
+var moderationCategories = []string{
+	"harassment",
+	"harassment/threatening",
+	"hate",
+	"hate/threatening",
+	"illicit",
+	"illicit/violent",
+	"self-harm",
+	"self-harm/intent",
+	"self-harm/instructions",
+	"sexual",
+	"sexual/minors",
+	"violence",
+	"violence/graphic",
+}
+
+// moderationInput is one item to classify
+type moderationInput struct {
+	text   string
+	images []string // Base64 encoded
+}
+
+// ModerationHandler classifies inputs with a local guard model such as Llama Guard
+func ModerationHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, client ollama.ClientInterface, usage middleware.UsageTracker) {
+	if r.Method != http.MethodPost {
+		writeError(w, errors.ErrMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, errors.ErrInvalidRequest.WithMessage("Failed to read request body"))
+		return
+	}
+
+	var req openai.ModerationRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+
+	// OpenAI moderation models are served by the configured guard model
+	model := req.Model
+	if model == "" || strings.HasPrefix(model, "omni-moderation") || strings.HasPrefix(model, "text-moderation") {
+		model = cfg.Moderation.Model
+	}
+
+	inputs, apiErr := parseModerationInputs(req.Input)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), cfg.GetTimeout())
+	defer cancel()
+
+	alias := getAliasFromRequest(r, cfg)
+
+	results := make([]openai.ModerationResult, len(inputs))
+	for i, input := range inputs {
+		guardReq := &ollama.ChatRequest{
+			Model: model,
+			Messages: []ollama.ChatMessage{
+				{Role: "user", Content: input.text, Images: input.images},
+			},
+			Options: map[string]interface{}{
+				"temperature": 0,
+			},
+		}
+		resp, err := client.Chat(ctx, guardReq)
+		if err != nil {
+			writeError(w, errors.FromUpstream(err, fmt.Sprintf("Ollama error: %v", err)))
+			return
+		}
+		usage.RecordCompletion(alias, servedModel(model, guardReq, resp.Model), int64(resp.PromptEvalCount), int64(resp.EvalCount))
+
+		flagged, categories := parseGuardVerdict(resp.Message.Content, cfg.Moderation.Categories)
+		results[i] = buildModerationResult(input, flagged, categories)
+	}
+
+	response := openai.ModerationResponse{
+		ID:      fmt.Sprintf("modr-%s", generateID()),
+		Model:   model,
+		Results: results,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseModerationInputs accepts a string, a list of strings (one result each),
+// or a list of text and image_url parts (one combined result)
+func parseModerationInputs(input interface{}) ([]moderationInput, *errors.APIError) {
+	switch v := input.(type) {
+	case string:
+		return []moderationInput{{text: v}}, nil
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, errors.ErrInvalidRequest.WithMessage("'input' must not be empty")
+		}
+
+		if _, ok := v[0].(map[string]interface{}); ok {
+			content := buildContentFromParts(v)
+			if content.text == "" && len(content.images) == 0 {
+				return nil, errors.ErrInvalidRequest.WithMessage("'input' must contain text or base64 image_url parts")
+			}
+			return []moderationInput{{text: content.text, images: content.images}}, nil
+		}
+
+		inputs := make([]moderationInput, len(v))
+		for i, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				return nil, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("'input[%d]' must be a string", i))
+			}
+			inputs[i] = moderationInput{text: text}
+		}
+		return inputs, nil
+	case nil:
+		return nil, errors.ErrInvalidRequest.WithMessage("'input' is required")
+	default:
+		return nil, errors.ErrInvalidRequest.WithMessage("'input' must be a string, an array of strings or an array of content parts")
+	}
+}
+
+// parseGuardVerdict reads a guard answer such as "safe" or "unsafe\nS1,S10",
+// mapping the listed category codes to OpenAI categories
+func parseGuardVerdict(output string, mapping map[string][]string) (bool, []string) {
+	fields := strings.FieldsFunc(output, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(fields) == 0 || !strings.EqualFold(fields[0], "unsafe") {
+		return false, nil
+	}
+
+	var categories []string
+	for _, code := range fields[1:] {
+		for guardCode, mapped := range mapping {
+			if strings.EqualFold(code, guardCode) {
+				categories = append(categories, mapped...)
+			}
+		}
+	}
+	return true, categories
+}
+
+// buildModerationResult fills every OpenAI category; the guard gives no
+// probabilities, so scores are 1 for flagged categories and 0 otherwise
+func buildModerationResult(input moderationInput, flagged bool, flaggedCategories []string) openai.ModerationResult {
+	var inputTypes []string
+	if input.text != "" {
+		inputTypes = append(inputTypes, "text")
+	}
+	if len(input.images) > 0 {
+		inputTypes = append(inputTypes, "image")
+	}
+
+	result := openai.ModerationResult{
+		Flagged:                   flagged,
+		Categories:                make(map[string]bool, len(moderationCategories)),
+		CategoryScores:            make(map[string]float64, len(moderationCategories)),
+		CategoryAppliedInputTypes: make(map[string][]string, len(moderationCategories)),
+	}
+	for _, category := range moderationCategories {
+		result.Categories[category] = false
+		result.CategoryScores[category] = 0
+		result.CategoryAppliedInputTypes[category] = inputTypes
+	}
+	for _, category := range flaggedCategories {
+		result.Categories[category] = true
+		result.CategoryScores[category] = 1
+		result.CategoryAppliedInputTypes[category] = inputTypes
+	}
+	return result
+}
@@ -0,0 +1,147 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"ollama2openai/config"
+	"ollama2openai/middleware"
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/errors"
+)
+
+// ollamaAdminEndpoints change the models installed on the Ollama server
+var ollamaAdminEndpoints = []string{"/api/pull", "/api/push", "/api/create", "/api/copy", "/api/delete", "/api/blobs/"}
+
+// ollamaNativeEndpoints are the other native endpoints relayed to Ollama
+var ollamaNativeEndpoints = []string{"/api/chat", "/api/generate", "/api/embed", "/api/embeddings", "/api/show", "/api/tags", "/api/ps", "/api/version"}
+
+// nativeUsage holds the model and token counts Ollama reports in native responses
+type nativeUsage struct {
+	Model           string `json:"model"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+}
+
+// NativeHandler relays native Ollama API calls (/api/chat, /api/generate, ...)
+// and records the token counts found in the responses
+func NativeHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, client ollama.ClientInterface, usage middleware.UsageTracker) {
+	alias := getAliasFromRequest(r, cfg)
+
+	// Ollama redirects variants such as /api/delete/ to the endpoint itself, so
+	// the checks and the upstream call use the cleaned path
+	endpoint := strings.TrimSuffix(path.Clean(r.URL.Path), "/")
+	switch {
+	case isOllamaAdminEndpoint(endpoint):
+		if !cfg.IsAdmin(alias) {
+			writeOllamaError(w, http.StatusForbidden, fmt.Sprintf("%s requires an admin API key", endpoint))
+			return
+		}
+	case !isOllamaNativeEndpoint(endpoint):
+		writeOllamaError(w, http.StatusNotFound, fmt.Sprintf("%s not found", r.URL.Path))
+		return
+	}
+
+	target := endpoint
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+
+	resp, err := client.Forward(r.Context(), r.Method, target, r.Body, r.Header.Get("Content-Type"))
+	if err != nil {
+		apiErr := errors.FromUpstream(err, fmt.Sprintf("Ollama error: %v", err))
+		status := http.StatusBadGateway
+		if apiErr.RetryAfter > 0 {
+			// Every backend able to serve the request is failing fast
+			status = http.StatusServiceUnavailable
+		}
+		errors.SetRetryAfter(w, apiErr)
+		writeOllamaError(w, status, apiErr.Message)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, header := range []string{"Content-Type", "Content-Length", "Content-Disposition"} {
+		if value := resp.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(w, resp.Body)
+		return
+	}
+
+	switch endpoint {
+	case "/api/chat", "/api/generate":
+		counts := relayNDJSON(w, resp.Body)
+		usage.RecordCompletion(alias, counts.Model, int64(counts.PromptEvalCount), int64(counts.EvalCount))
+	case "/api/embed":
+		counts := relayNDJSON(w, resp.Body)
+		usage.RecordEmbedding(alias, int64(counts.PromptEvalCount))
+	default:
+		io.Copy(w, resp.Body)
+	}
+}
+
+// relayNDJSON copies a JSON or NDJSON body line by line, flushing each line
+// so streams reach the client unchanged, and sums the reported token counts
+func relayNDJSON(w http.ResponseWriter, body io.Reader) nativeUsage {
+	flusher, _ := w.(http.Flusher)
+	reader := bufio.NewReader(body)
+
+	var total nativeUsage
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			w.Write(line)
+			if flusher != nil {
+				flusher.Flush()
+			}
+
+			var counts nativeUsage
+			if json.Unmarshal(bytes.TrimSpace(line), &counts) == nil {
+				if counts.Model != "" {
+					total.Model = counts.Model
+				}
+				total.PromptEvalCount += counts.PromptEvalCount
+				total.EvalCount += counts.EvalCount
+			}
+		}
+		if err != nil {
+			return total
+		}
+	}
+}
+
+func isOllamaAdminEndpoint(p string) bool {
+	for _, endpoint := range ollamaAdminEndpoints {
+		if p == endpoint || (strings.HasSuffix(endpoint, "/") && strings.HasPrefix(p, endpoint)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isOllamaNativeEndpoint(p string) bool {
+	for _, endpoint := range ollamaNativeEndpoints {
+		if p == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOllamaError writes an error in Ollama's native {"error": "..."} format
+func writeOllamaError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,82 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Priority tracks in-flight interactive requests so background work such as
+// batches yields to them: while an interactive request is running, only
+// busyLimit background requests may reach Ollama at once
+type Priority struct {
+	mu         sync.Mutex
+	active     int // Interactive requests in flight
+	background int // Background requests in flight
+	busyLimit  int
+	changed    chan struct{} // Closed and replaced when either count drops
+}
+
+// NewPriority creates a priority gate letting busyLimit background requests
+// run alongside interactive traffic
+func NewPriority(busyLimit int) *Priority {
+	return &Priority{busyLimit: busyLimit, changed: make(chan struct{})}
+}
+
+// Track wraps an interactive handler so it holds off background work while running
+func (p *Priority) Track(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p.begin()
+		defer p.end()
+		handler(w, r)
+	}
+}
+
+func (p *Priority) begin() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active++
+}
+
+func (p *Priority) end() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.active--
+	p.signal()
+}
+
+// Acquire blocks until a background request may run or ctx is done, and
+// returns the function to call once it has finished
+func (p *Priority) Acquire(ctx context.Context) (func(), error) {
+	for {
+		p.mu.Lock()
+		if p.active == 0 || p.background < p.busyLimit {
+			p.background++
+			p.mu.Unlock()
+			return p.release, nil
+		}
+		changed := p.changed
+		p.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (p *Priority) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.background--
+	p.signal()
+}
+
+// signal wakes the background requests waiting for a slot; p.mu must be held
+func (p *Priority) signal() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
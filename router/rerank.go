@@ -0,0 +1,227 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"ollama2openai/cache"
+	"ollama2openai/config"
+	"ollama2openai/middleware"
+	"ollama2openai/openai"
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/errors"
+	"ollama2openai/pkg/vector"
+	"ollama2openai/tokenizer"
+)
+
+// rerankPrompt asks a reranker model to grade one document against the query
+const rerankPrompt = `Judge how relevant the document is to the query.
+Reply with a single number from 0 (irrelevant) to 10 (perfectly relevant) and nothing else.
+
+Query: %s
+
+Document: %s
+
+Relevance:`
+
+var rerankScorePattern = regexp.MustCompile(`\d+(?:\.\d+)?`)
+
+// RerankHandler handles Cohere/Jina style rerank requests
+func RerankHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, client ollama.ClientInterface, usage middleware.UsageTracker, embedCache cache.EmbeddingCache, digests *ModelDigests) {
+	if r.Method != http.MethodPost {
+		writeError(w, errors.ErrMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, errors.ErrInvalidRequest.WithMessage("Failed to read request body"))
+		return
+	}
+
+	var req openai.RerankRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+
+	// Use default model if not specified
+	if req.Model == "" {
+		req.Model = cfg.Rerank.DefaultModel
+	}
+
+	if req.Query == "" {
+		writeError(w, errors.ErrInvalidRequest.WithMessage("'query' is required"))
+		return
+	}
+
+	documents, apiErr := parseRerankDocuments(req.Documents)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	topN := len(documents)
+	if req.TopN != nil {
+		if *req.TopN <= 0 {
+			writeError(w, errors.ErrInvalidRequest.WithMessage("top_n must be a positive integer"))
+			return
+		}
+		topN = min(*req.TopN, len(documents))
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), cfg.GetTimeout())
+	defer cancel()
+
+	alias := getAliasFromRequest(r, cfg)
+
+	var scores []float64
+	var totalTokens int
+
+	if cfg.IsRerankerModel(req.Model) {
+		var promptTokens, completionTokens int
+		scores, promptTokens, completionTokens, err = scoreWithReranker(ctx, client, req.Model, req.Query, documents)
+		if err == nil {
+			usage.RecordCompletion(alias, req.Model, int64(promptTokens), int64(completionTokens))
+			totalTokens = promptTokens + completionTokens
+		}
+	} else {
+		e := &embedder{
+			client:    client,
+			batchSize: cfg.Embeddings.BatchSize,
+			cache:     embedCache,
+			digests:   digests,
+		}
+		scores, totalTokens, err = scoreWithEmbeddings(ctx, e, req.Model, req.Query, documents)
+		if err == nil {
+			usage.RecordEmbedding(alias, int64(totalTokens))
+		}
+	}
+	if err != nil {
+		writeError(w, errors.FromUpstream(err, fmt.Sprintf("Ollama error: %v", err)))
+		return
+	}
+
+	results := make([]openai.RerankResult, len(documents))
+	for i, score := range scores {
+		results[i] = openai.RerankResult{Index: i, RelevanceScore: score}
+		if req.ReturnDocuments == nil || *req.ReturnDocuments {
+			results[i].Document = &openai.RerankDocument{Text: documents[i]}
+		}
+	}
+
+	// Highest score first, keeping request order for ties
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].RelevanceScore > results[j].RelevanceScore
+	})
+
+	response := openai.RerankResponse{
+		ID:      fmt.Sprintf("rerank-%s", generateID()),
+		Model:   req.Model,
+		Results: results[:topN],
+		Usage: openai.EmbeddingUsage{
+			PromptTokens: totalTokens,
+			TotalTokens:  totalTokens,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseRerankDocuments accepts documents as strings or objects with a "text" field
+func parseRerankDocuments(documents []interface{}) ([]string, *errors.APIError) {
+	if len(documents) == 0 {
+		return nil, errors.ErrInvalidRequest.WithMessage("'documents' must not be empty")
+	}
+
+	texts := make([]string, len(documents))
+	for i, doc := range documents {
+		switch d := doc.(type) {
+		case string:
+			texts[i] = d
+		case map[string]interface{}:
+			text, ok := d["text"].(string)
+			if !ok {
+				return nil, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("'documents[%d]' must have a string 'text' field", i))
+			}
+			texts[i] = text
+		default:
+			return nil, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("'documents[%d]' must be a string or an object with a 'text' field", i))
+		}
+	}
+	return texts, nil
+}
+
+// scoreWithEmbeddings scores documents by cosine similarity to the query embedding
+func scoreWithEmbeddings(ctx context.Context, e *embedder, model, query string, documents []string) ([]float64, int, error) {
+	inputs := append([]string{query}, documents...)
+
+	embeddings, tokens, err := e.embed(ctx, model, inputs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	scores := make([]float64, len(documents))
+	for i := range documents {
+		scores[i] = vector.Cosine(embeddings[0], embeddings[i+1])
+	}
+	return scores, tokens, nil
+}
+
+// scoreWithReranker prompts a reranker model for each document's relevance,
+// scaling its 0-10 answer to a score between 0 and 1
+func scoreWithReranker(ctx context.Context, client ollama.ClientInterface, model, query string, documents []string) ([]float64, int, int, error) {
+	scores := make([]float64, len(documents))
+	promptTokens, completionTokens := 0, 0
+
+	for i, doc := range documents {
+		prompt := fmt.Sprintf(rerankPrompt, query, doc)
+		resp, err := client.Generate(ctx, &ollama.GenerateRequest{
+			Model:  model,
+			Prompt: prompt,
+			Options: map[string]interface{}{
+				"temperature": 0,
+				"num_predict": 8,
+			},
+		})
+		if err != nil {
+			return nil, 0, 0, err
+		}
+
+		scores[i] = parseRerankScore(resp.Response)
+
+		if resp.PromptEvalCount > 0 {
+			promptTokens += resp.PromptEvalCount
+		} else {
+			promptTokens += tokenizer.EstimateTokenCount(prompt)
+		}
+		if resp.EvalCount > 0 {
+			completionTokens += resp.EvalCount
+		} else {
+			completionTokens += tokenizer.EstimateTokenCount(resp.Response)
+		}
+	}
+
+	return scores, promptTokens, completionTokens, nil
+}
+
+// parseRerankScore reads the first number in a reranker reply, treating
+// an unparseable reply as irrelevant
+func parseRerankScore(reply string) float64 {
+	match := rerankScorePattern.FindString(reply)
+	if match == "" {
+		return 0
+	}
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0
+	}
+	return min(score, 10) / 10
+}
@@ -1,10 +1,14 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
 	"ollama2openai/config"
@@ -12,12 +16,13 @@ import (
 	"ollama2openai/openai"
 	"ollama2openai/ollama"
 	"ollama2openai/pkg/errors"
+	"ollama2openai/store"
 	"ollama2openai/tokenizer"
 )
 
 // ResponseHandler handles Response API requests (simplified implementation)
 // The Response API is a newer OpenAI API that combines chat, tools, and vision
-func ResponseHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, client ollama.ClientInterface, usage middleware.UsageTracker) {
+func ResponseHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, client ollama.ClientInterface, usage middleware.UsageTracker, responses store.ResponseStore, background *BackgroundResponses, vectorStores *VectorStores) {
 	if r.Method != http.MethodPost {
 		writeError(w, errors.ErrMethodNotAllowed)
 		return
@@ -46,11 +51,49 @@ func ResponseHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config,
 	// This is a simplified implementation
 	messages := extractMessagesFromInput(req.Input)
 
+	// Rebuild the earlier conversation when continuing from a stored response
+	history, apiErr := loadConversation(responses, req.PreviousResponseID, alias)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	conversation := make([]openai.ChatMessage, 0, len(history)+len(messages))
+	conversation = append(conversation, history...)
+	conversation = append(conversation, messages...)
+
+	ctx := withSession(r.Context(), r, cfg, "")
+
+	// Instructions and file_search results apply to this request only and are not carried over
+	var preamble []openai.ChatMessage
+	if req.Instructions != "" {
+		preamble = append(preamble, openai.ChatMessage{Role: "system", Content: req.Instructions})
+	}
+
+	searchCall, searchContext, searchTokens, apiErr := fileSearch(ctx, vectorStores, alias, req.Tools, messages, slices.Contains(req.Include, "file_search_call.results"))
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+	if searchCall != nil {
+		usage.RecordEmbedding(alias, int64(searchTokens))
+	}
+	if searchContext != nil {
+		preamble = append(preamble, *searchContext)
+	}
+
+	chatMessages := conversation
+	if len(preamble) > 0 {
+		chatMessages = append(preamble, conversation...)
+	}
+
 	// Convert to chat completion request
 	chatReq := &openai.ChatCompletionRequest{
-		Model:    req.Model,
-		Messages: messages,
-		Stream:   req.Stream,
+		Model:      req.Model,
+		Messages:   chatMessages,
+		Stream:     req.Stream,
+		Tools:      convertResponseTools(req.Tools),
+		ToolChoice: req.ToolChoice,
 	}
 
 	if req.MaxOutputTokens != nil {
@@ -60,16 +103,44 @@ func ResponseHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config,
 		chatReq.Temperature = req.Temperature
 	}
 
-	ctx := r.Context()
+	response := openai.ResponseResponse{
+		ID:                 fmt.Sprintf("resp_%s", generateID()),
+		Object:             "response",
+		Created:            time.Now().Unix(),
+		Model:              req.Model,
+		Status:             "completed",
+		PreviousResponseID: req.PreviousResponseID,
+	}
+	if searchCall != nil {
+		response.Output = []openai.OutputItem{*searchCall}
+	}
+
+	stored := &store.StoredResponse{
+		Alias:      alias,
+		InputItems: buildInputItems(messages),
+		CreatedAt:  time.Now(),
+	}
+	shouldStore := responses != nil && (req.Store == nil || *req.Store)
+
+	if req.Background {
+		if !shouldStore {
+			writeError(w, errors.ErrInvalidRequest.WithMessage("Background responses require response storage to be enabled"))
+			return
+		}
+		startBackgroundResponse(w, r, cfg, client, usage, responses, background, &req, response, stored, conversation, chatReq)
+		return
+	}
 
 	if req.Stream {
-		// For streaming, we'll redirect to chat handler logic
 		ollamaReq, err := convertChatRequest(chatReq)
 		if err != nil {
 			writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Failed to convert request: %v", err)))
 			return
 		}
-		handleStreamingChat(ctx, w, cfg, client, chatReq, ollamaReq, alias, usage)
+		if !shouldStore {
+			responses = nil
+		}
+		handleStreamingResponse(ctx, w, client, usage, responses, response, stored, conversation, chatReq, ollamaReq)
 		return
 	}
 
@@ -82,48 +153,531 @@ func ResponseHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config,
 
 	resp, err := client.Chat(ctx, ollamaReq)
 	if err != nil {
-		writeError(w, errors.ErrOllamaConnection.WithMessage(fmt.Sprintf("Ollama error: %v", err)))
+		writeError(w, errors.FromUpstream(err, fmt.Sprintf("Ollama error: %v", err)))
 		return
 	}
 
+	assistant := openai.ChatMessage{
+		Role:      "assistant",
+		Content:   resp.Message.Content,
+		ToolCalls: convertToolCalls(resp.Message.ToolCalls),
+	}
+
 	// Calculate tokens
 	promptTokens := estimatePromptTokens(chatReq)
-	completionTokens := tokenizer.EstimateTokenCount(resp.Message.Content)
+	completionTokens := estimateAssistantTokens(assistant)
 
-	usage.RecordCompletion(alias, int64(promptTokens), int64(completionTokens))
+	response.Model = servedModel(req.Model, ollamaReq, resp.Model)
+	usage.RecordCompletion(alias, response.Model, int64(promptTokens), int64(completionTokens))
 
 	// Build Response API format
-	response := openai.ResponseResponse{
-		ID:      fmt.Sprintf("resp_%s", generateID()),
-		Object:  "response",
-		Created: time.Now().Unix(),
-		Model:   req.Model,
-		Output: []openai.OutputItem{
-			{
-				Type: "message",
-				Content: []openai.ContentPart{
-					{
-						Type: "text",
-						Text: resp.Message.Content,
-					},
-				},
-				Role: "assistant",
-			},
-		},
-		Usage: openai.Usage{
-			PromptTokens:     promptTokens,
-			CompletionTokens: completionTokens,
-			TotalTokens:      promptTokens + completionTokens,
-		},
+	response.Output = append(response.Output, buildOutputItems(assistant)...)
+	response.Usage = openai.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+
+	if shouldStore {
+		stored.Response = response
+		stored.Messages = appendAssistantMessage(conversation, assistant)
+		if err := responses.Save(stored); err != nil {
+			writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to store response: %v", err)))
+			return
+		}
+	}
+
+	w.Header().Set(servedModelHeader, response.Model)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleStreamingResponse streams a response as Response API events carrying its
+// ID, then saves it when responses is not nil
+func handleStreamingResponse(ctx context.Context, w http.ResponseWriter, client ollama.ClientInterface, usage middleware.UsageTracker, responses store.ResponseStore, response openai.ResponseResponse, stored *store.StoredResponse, conversation []openai.ChatMessage, chatReq *openai.ChatCompletionRequest, ollamaReq *ollama.ChatRequest) {
+	ollamaReq.Stream = true
+	stream, err := client.ChatStream(ctx, ollamaReq)
+	if err != nil {
+		writeError(w, errors.FromUpstream(err, fmt.Sprintf("Failed to start streaming: %v", err)))
+		return
+	}
+	defer stream.Close()
+	response.Model = servedModel(response.Model, ollamaReq, stream.Model())
+
+	w.Header().Set(servedModelHeader, response.Model)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	flusher, _ := w.(http.Flusher)
+	sequence := 0
+	send := func(event openai.ResponseStreamEvent) {
+		event.SequenceNumber = sequence
+		sequence++
+		writeResponseEvent(w, event)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	response.Status = "in_progress"
+	if response.Output == nil {
+		response.Output = []openai.OutputItem{}
+	}
+	created := response
+	send(openai.ResponseStreamEvent{Type: "response.created", Response: &created})
+	inProgress := response
+	send(openai.ResponseStreamEvent{Type: "response.in_progress", Response: &inProgress})
+
+	// Output items from before generation, such as file_search calls, come first
+	itemID := fmt.Sprintf("msg_%s", generateID())
+	outputIndex := len(response.Output)
+
+	assistant, err := readResponseStream(ctx, stream, itemID, outputIndex, send)
+	stored.Messages = conversation
+	if err != nil {
+		// A turn cut short is kept as failed so later turns do not build on it
+		failResponse(&response, ctx, err)
+	} else {
+		completeResponse(&response, chatReq, assistant, itemID, outputIndex)
+		usage.RecordCompletion(stored.Alias, response.Model, int64(response.Usage.PromptTokens), int64(response.Usage.CompletionTokens))
+		stored.Messages = appendAssistantMessage(conversation, assistant)
+	}
+
+	if responses != nil {
+		stored.Response = response
+		if err := responses.Save(stored); err != nil {
+			send(openai.ResponseStreamEvent{
+				Type:    "error",
+				Code:    "server_error",
+				Message: fmt.Sprintf("Failed to store response: %v", err),
+			})
+			return
+		}
+	}
+	send(openai.ResponseStreamEvent{Type: "response." + response.Status, Response: &response})
+}
+
+// ResponseItemHandler handles retrieval, deletion, cancellation and input item listing for stored responses
+// Path format: /v1/responses/{id}, /v1/responses/{id}/input_items or /v1/responses/{id}/cancel
+func ResponseItemHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, responses store.ResponseStore, background *BackgroundResponses) {
+	parts := splitPath(r.URL.Path)
+	if len(parts) < 3 || len(parts) > 4 {
+		writeError(w, errors.ErrNotFound)
+		return
+	}
+
+	alias := getAliasFromRequest(r, cfg)
+	stored, apiErr := getStoredResponse(responses, parts[2], alias)
+	if apiErr == nil {
+		stored = reconcileBackgroundResponse(responses, background, stored)
+	}
+
+	if len(parts) == 4 {
+		var method string
+		switch parts[3] {
+		case "input_items":
+			method = http.MethodGet
+		case "cancel":
+			method = http.MethodPost
+		default:
+			writeError(w, errors.ErrNotFound)
+			return
+		}
+		if r.Method != method {
+			writeError(w, errors.ErrMethodNotAllowed)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+		if parts[3] == "cancel" {
+			cancelBackgroundResponse(w, responses, background, stored)
+			return
+		}
+		listInputItems(w, r, stored)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+		if r.URL.Query().Get("stream") == "true" {
+			after := -1
+			if v := r.URL.Query().Get("starting_after"); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					writeError(w, errors.ErrInvalidRequest.WithMessage("starting_after must be an integer"))
+					return
+				}
+				after = n
+			}
+			streamResponseEvents(w, r, background.get(stored.Response.ID), stored, after)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stored.Response)
+	case http.MethodDelete:
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+		// Stop a running worker first so it cannot save the response again
+		if task := background.get(stored.Response.ID); task != nil {
+			task.requestCancel()
+			<-task.done
+		}
+		if err := responses.Delete(stored.Response.ID); err != nil && !stderrors.Is(err, store.ErrNotFound) {
+			writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to delete response: %v", err)))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openai.DeletedObject{
+			ID:      stored.Response.ID,
+			Object:  "response",
+			Deleted: true,
+		})
+	default:
+		writeError(w, errors.ErrMethodNotAllowed)
+	}
+}
+
+// startBackgroundResponse queues a response and generates it in a worker goroutine,
+// returning the queued response or streaming its events when stream is set
+func startBackgroundResponse(w http.ResponseWriter, r *http.Request, cfg *config.Config, client ollama.ClientInterface, usage middleware.UsageTracker, responses store.ResponseStore, background *BackgroundResponses, req *openai.ResponseRequest, response openai.ResponseResponse, stored *store.StoredResponse, conversation []openai.ChatMessage, chatReq *openai.ChatCompletionRequest) {
+	ollamaReq, err := convertChatRequest(chatReq)
+	if err != nil {
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Failed to convert request: %v", err)))
+		return
+	}
+
+	response.Status = "queued"
+	response.Background = true
+	if response.Output == nil {
+		response.Output = []openai.OutputItem{}
+	}
+	stored.Response = response
+	stored.Messages = conversation
+
+	if err := responses.Save(stored); err != nil {
+		writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to store response: %v", err)))
+		return
+	}
+
+	// The worker outlives the HTTP request, so it gets its own context
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.GetTimeout())
+	task := background.start(response.ID, cancel)
+
+	created := response
+	task.publish(openai.ResponseStreamEvent{Type: "response.created", Response: &created})
+	queued := response
+	task.publish(openai.ResponseStreamEvent{Type: "response.queued", Response: &queued})
+
+	// The worker owns its copy of the stored response from here on
+	workerStored := *stored
+	go runBackgroundResponse(ctx, background, task, client, usage, responses, &workerStored, chatReq, ollamaReq)
+
+	if req.Stream {
+		streamResponseEvents(w, r, task, nil, -1)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// cancelBackgroundResponse aborts a running background response and returns its final state
+func cancelBackgroundResponse(w http.ResponseWriter, responses store.ResponseStore, background *BackgroundResponses, stored *store.StoredResponse) {
+	if !stored.Response.Background {
+		writeError(w, errors.ErrInvalidRequest.WithMessage("Only background responses can be cancelled"))
+		return
+	}
+
+	if task := background.get(stored.Response.ID); task != nil && task.requestCancel() {
+		<-task.done
+		if latest, err := responses.Get(stored.Response.ID); err == nil {
+			stored = latest
+		}
+	}
+
+	if stored.Response.Status != "cancelled" {
+		writeError(w, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Cannot cancel a response with status '%s'", stored.Response.Status)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stored.Response)
+}
+
+// reconcileBackgroundResponse marks background responses left unfinished by a restart as failed
+func reconcileBackgroundResponse(responses store.ResponseStore, background *BackgroundResponses, stored *store.StoredResponse) *store.StoredResponse {
+	status := stored.Response.Status
+	if !stored.Response.Background || (status != "queued" && status != "in_progress") {
+		return stored
+	}
+	if background.get(stored.Response.ID) != nil {
+		return stored
+	}
+
+	// Re-read in case the worker finished between the lookup and now
+	if latest, err := responses.Get(stored.Response.ID); err == nil && latest.Response.Status != status {
+		return latest
+	}
+
+	failed := *stored
+	failed.Response.Status = "failed"
+	failed.Response.Error = &openai.ErrorDetail{
+		Message: "Response generation was interrupted",
+		Type:    "server_error",
+		Code:    "server_error",
+	}
+	responses.Save(&failed)
+	return &failed
+}
+
+// listInputItems writes a page of input items, honouring limit, order and after
+func listInputItems(w http.ResponseWriter, r *http.Request, stored *store.StoredResponse) {
+	query := r.URL.Query()
+
+	limit := 20
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			writeError(w, errors.ErrInvalidRequest.WithMessage("limit must be between 1 and 100"))
+			return
+		}
+		limit = n
+	}
+
+	items := make([]openai.InputItem, len(stored.InputItems))
+	copy(items, stored.InputItems)
+
+	switch query.Get("order") {
+	case "", "desc":
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	case "asc":
+	default:
+		writeError(w, errors.ErrInvalidRequest.WithMessage("order must be 'asc' or 'desc'"))
+		return
+	}
+
+	if after := query.Get("after"); after != "" {
+		for i, item := range items {
+			if item.ID == after {
+				items = items[i+1:]
+				break
+			}
+		}
+	}
+
+	list := openai.InputItemList{
+		Object: "list",
+		Data:   items,
+	}
+	if len(items) > limit {
+		list.Data = items[:limit]
+		list.HasMore = true
+	}
+	if len(list.Data) > 0 {
+		list.FirstID = list.Data[0].ID
+		list.LastID = list.Data[len(list.Data)-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// getStoredResponse loads a stored response, hiding responses owned by other aliases
+func getStoredResponse(responses store.ResponseStore, id, alias string) (*store.StoredResponse, *errors.APIError) {
+	if responses == nil {
+		return nil, errors.ErrNotFound.WithMessage(fmt.Sprintf("Response '%s' not found", id))
+	}
+
+	stored, err := responses.Get(id)
+	if err != nil {
+		if stderrors.Is(err, store.ErrNotFound) {
+			return nil, errors.ErrNotFound.WithMessage(fmt.Sprintf("Response '%s' not found", id))
+		}
+		return nil, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to load response: %v", err))
+	}
+
+	if stored.Alias != alias {
+		return nil, errors.ErrNotFound.WithMessage(fmt.Sprintf("Response '%s' not found", id))
+	}
+
+	return stored, nil
+}
+
+// loadConversation returns the messages of a previous response, if one was referenced
+func loadConversation(responses store.ResponseStore, previousID, alias string) ([]openai.ChatMessage, *errors.APIError) {
+	if previousID == "" {
+		return nil, nil
+	}
+
+	stored, apiErr := getStoredResponse(responses, previousID, alias)
+	if apiErr != nil {
+		return nil, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Previous response '%s' not found", previousID))
+	}
+
+	return stored.Messages, nil
+}
+
+// convertResponseTools converts Response API function tools to chat completion tools
+func convertResponseTools(tools []openai.ResponseTool) []openai.Tool {
+	var result []openai.Tool
+	for _, tool := range tools {
+		if tool.Type != "function" {
+			continue
+		}
+		if tool.Function != nil {
+			result = append(result, openai.Tool{Type: "function", Function: tool.Function})
+			continue
+		}
+		result = append(result, openai.Tool{
+			Type: "function",
+			Function: &openai.ToolFunc{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	return result
+}
+
+// buildOutputItems converts an assistant message to a message item and one function_call item per tool call
+func buildOutputItems(assistant openai.ChatMessage) []openai.OutputItem {
+	items := []openai.OutputItem{}
+
+	content, _ := assistant.Content.(string)
+	if content != "" || len(assistant.ToolCalls) == 0 {
+		items = append(items, openai.OutputItem{
+			ID:     fmt.Sprintf("msg_%s", generateID()),
+			Type:   "message",
+			Status: "completed",
+			Content: []openai.ContentPart{
+				{
+					Type: "text",
+					Text: content,
+				},
+			},
+			Role: "assistant",
+		})
+	}
+
+	for _, tc := range assistant.ToolCalls {
+		if tc.Function == nil {
+			continue
+		}
+		items = append(items, openai.OutputItem{
+			ID:        fmt.Sprintf("fc_%s", generateID()),
+			Type:      "function_call",
+			Status:    "completed",
+			CallID:    tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		})
+	}
+
+	return items
+}
+
+func buildInputItems(messages []openai.ChatMessage) []openai.InputItem {
+	items := make([]openai.InputItem, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == "tool" {
+			output, _ := msg.Content.(string)
+			items = append(items, openai.InputItem{
+				ID:     fmt.Sprintf("fco_%s", generateID()),
+				Type:   "function_call_output",
+				CallID: msg.ToolCallID,
+				Output: output,
+			})
+			continue
+		}
+
+		for _, tc := range msg.ToolCalls {
+			if tc.Function == nil {
+				continue
+			}
+			items = append(items, openai.InputItem{
+				ID:        fmt.Sprintf("fc_%s", generateID()),
+				Type:      "function_call",
+				CallID:    tc.ID,
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			})
+		}
+		if len(msg.ToolCalls) > 0 && msg.Content == nil {
+			continue
+		}
+
+		items = append(items, openai.InputItem{
+			ID:      fmt.Sprintf("msg_%s", generateID()),
+			Type:    "message",
+			Role:    msg.Role,
+			Content: inputContentParts(msg.Content),
+		})
+	}
+	return items
+}
+
+// inputContentParts converts chat message content to Response API input content parts
+func inputContentParts(content interface{}) []openai.ContentPart {
+	switch c := content.(type) {
+	case string:
+		return []openai.ContentPart{{Type: "input_text", Text: c}}
+	case []interface{}:
+		parts := []openai.ContentPart{}
+		for _, part := range c {
+			partMap, ok := part.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch partMap["type"] {
+			case "text":
+				text, _ := partMap["text"].(string)
+				parts = append(parts, openai.ContentPart{Type: "input_text", Text: text})
+			case "image_url":
+				if imageURL, ok := partMap["image_url"].(map[string]interface{}); ok {
+					url, _ := imageURL["url"].(string)
+					parts = append(parts, openai.ContentPart{Type: "input_image", ImageURL: &openai.ImageURL{URL: url}})
+				}
+			}
+		}
+		return parts
+	}
+	return nil
+}
+
+func appendAssistantMessage(conversation []openai.ChatMessage, assistant openai.ChatMessage) []openai.ChatMessage {
+	messages := make([]openai.ChatMessage, 0, len(conversation)+1)
+	messages = append(messages, conversation...)
+	return append(messages, assistant)
+}
+
+// estimateAssistantTokens estimates completion tokens for content and tool call arguments
+func estimateAssistantTokens(assistant openai.ChatMessage) int {
+	content, _ := assistant.Content.(string)
+	tokens := tokenizer.EstimateTokenCount(content)
+	for _, tc := range assistant.ToolCalls {
+		if tc.Function != nil {
+			tokens += tokenizer.EstimateTokenCount(tc.Function.Name) + tokenizer.EstimateTokenCount(tc.Function.Arguments)
+		}
+	}
+	return tokens
+}
+
 func extractMessagesFromInput(input interface{}) []openai.ChatMessage {
 	// Extract messages from input - this is a simplified implementation
-	// In the Response API, input can be a string or array of content
+	// In the Response API, input can be a string or array of items:
+	// messages, function_call items echoed back by the client, and function_call_output items
 
 	messages := []openai.ChatMessage{}
 
@@ -135,16 +689,60 @@ func extractMessagesFromInput(input interface{}) []openai.ChatMessage {
 		})
 	case []interface{}:
 		for _, item := range v {
-			if msgMap, ok := item.(map[string]interface{}); ok {
-				if content, ok := msgMap["content"].(string); ok {
-					role := "user"
-					if r, ok := msgMap["role"].(string); ok {
-						role = r
-					}
+			msgMap, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			itemType, _ := msgMap["type"].(string)
+			switch itemType {
+			case "function_call":
+				callID, _ := msgMap["call_id"].(string)
+				name, _ := msgMap["name"].(string)
+				arguments, _ := msgMap["arguments"].(string)
+				call := openai.ToolCall{
+					ID:       callID,
+					Type:     "function",
+					Function: &openai.ToolCallFunction{Name: name, Arguments: arguments},
+				}
+
+				// Parallel calls are grouped into a single assistant message
+				if n := len(messages); n > 0 && messages[n-1].Role == "assistant" && len(messages[n-1].ToolCalls) > 0 {
+					messages[n-1].ToolCalls = append(messages[n-1].ToolCalls, call)
+					continue
+				}
+				messages = append(messages, openai.ChatMessage{
+					Role:      "assistant",
+					ToolCalls: []openai.ToolCall{call},
+				})
+			case "function_call_output":
+				callID, _ := msgMap["call_id"].(string)
+				output, ok := msgMap["output"].(string)
+				if !ok {
+					data, _ := json.Marshal(msgMap["output"])
+					output = string(data)
+				}
+				messages = append(messages, openai.ChatMessage{
+					Role:       "tool",
+					Content:    output,
+					ToolCallID: callID,
+				})
+			case "", "message":
+				role := "user"
+				if r, ok := msgMap["role"].(string); ok {
+					role = r
+				}
+				switch content := msgMap["content"].(type) {
+				case string:
 					messages = append(messages, openai.ChatMessage{
 						Role:    role,
 						Content: content,
 					})
+				case []interface{}:
+					messages = append(messages, openai.ChatMessage{
+						Role:    role,
+						Content: convertInputContent(content),
+					})
 				}
 			}
 		}
@@ -152,3 +750,29 @@ func extractMessagesFromInput(input interface{}) []openai.ChatMessage {
 
 	return messages
 }
+
+// convertInputContent maps Response API content parts (input_text, output_text, input_image)
+// to chat completion content parts
+func convertInputContent(parts []interface{}) []interface{} {
+	result := make([]interface{}, 0, len(parts))
+	for _, part := range parts {
+		partMap, ok := part.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch partMap["type"] {
+		case "input_text", "output_text", "text":
+			result = append(result, map[string]interface{}{
+				"type": "text",
+				"text": partMap["text"],
+			})
+		case "input_image":
+			url, _ := partMap["image_url"].(string)
+			result = append(result, map[string]interface{}{
+				"type":      "image_url",
+				"image_url": map[string]interface{}{"url": url},
+			})
+		}
+	}
+	return result
+}
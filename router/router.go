@@ -3,29 +3,69 @@ package router
 import (
 	"net/http"
 
+	"ollama2openai/batch"
+	"ollama2openai/cache"
 	"ollama2openai/config"
+	"ollama2openai/filestore"
 	"ollama2openai/middleware"
-	"ollama2openai/ollama"
 	"ollama2openai/pkg/errors"
 	"ollama2openai/pkg/logger"
+	"ollama2openai/provider"
+	"ollama2openai/store"
+	"ollama2openai/tokenizer"
+	"ollama2openai/vectorstore"
 )
 
 // Router encapsulates the dependencies for handling requests
 type Router struct {
-	client ollama.ClientInterface
-	config *config.Config
-	usage  middleware.UsageTracker
-	logger logger.Logger
+	client     provider.Provider
+	backends   *provider.Pool
+	config     *config.Config
+	usage      middleware.UsageTracker
+	logger     logger.Logger
+	responses  store.ResponseStore
+	background *BackgroundResponses
+	embedCache cache.EmbeddingCache
+	digests    *ModelDigests
+	vocabs     *tokenizer.Registry
+	vectors    *VectorStores
+	files      filestore.Storage
+	priority   *Priority
+	batches    *BatchRunner
 }
 
 // NewRouter creates a new Router instance
-func NewRouter(cfg *config.Config, client ollama.ClientInterface, usage middleware.UsageTracker, log logger.Logger) *Router {
-	return &Router{
-		client: client,
-		config: cfg,
-		usage:  usage,
-		logger: log,
+// responses and embedCache may be nil to disable response storage and embedding caching
+func NewRouter(cfg *config.Config, client provider.Provider, backends *provider.Pool, usage middleware.UsageTracker, log logger.Logger, responses store.ResponseStore, embedCache cache.EmbeddingCache, vectorIndex vectorstore.Index, files filestore.Storage, batches batch.Store) *Router {
+	rt := &Router{
+		client:     client,
+		backends:   backends,
+		config:     cfg,
+		usage:      usage,
+		logger:     log,
+		responses:  responses,
+		background: NewBackgroundResponses(),
+		embedCache: embedCache,
+		vocabs:     tokenizer.NewRegistry(cfg.Tokenizers),
+		files:      files,
+		priority:   NewPriority(cfg.Batches.BusyWorkers),
 	}
+
+	if embedCache != nil {
+		rt.digests = NewModelDigests(client, cfg.GetDigestRefresh(), func(model, digest string) {
+			log.Info("Model digest changed, invalidating cached embeddings",
+				logger.String("model", model),
+				logger.String("digest", digest),
+			)
+			embedCache.Invalidate(model, digest)
+		})
+	}
+	rt.vectors = NewVectorStores(cfg, client, vectorIndex, files, embedCache, rt.digests)
+
+	// Batch lines bypass the priority gate, which only tracks interactive traffic
+	rt.batches = NewBatchRunner(cfg, batches, files, rt.priority, rt.modelEndpoints(), log)
+
+	return rt
 }
 
 // SetupRoutes initializes all routes
@@ -38,30 +78,114 @@ func (rt *Router) SetupRoutes(mux *http.ServeMux) {
 		UsageHandler(w, r, rt.config)
 	})
 
-	// OpenAI-compatible endpoints
-	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
-		ChatHandler(w, r, rt.config, rt.client, rt.usage)
+	// Ollama backend health and load
+	mux.HandleFunc("/backends", func(w http.ResponseWriter, r *http.Request) {
+		BackendsHandler(w, r, rt.backends)
 	})
 
-	mux.HandleFunc("/v1/embeddings", func(w http.ResponseWriter, r *http.Request) {
-		EmbeddingHandler(w, r, rt.config, rt.client, rt.usage)
+	// Embedding cache statistics
+	mux.HandleFunc("/cache", func(w http.ResponseWriter, r *http.Request) {
+		EmbeddingCacheHandler(w, r, rt.config, rt.embedCache)
 	})
 
-	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+	// OpenAI-compatible endpoints
+	// Interactive endpoints hold off batch work while they run
+	mux.HandleFunc("/v1/chat/completions", rt.priority.Track(rt.chat))
+
+	mux.HandleFunc("/v1/embeddings", rt.priority.Track(rt.embeddings))
+
+	mux.HandleFunc("/v1/rerank", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		RerankHandler(w, r, rt.config, rt.client, rt.usage, rt.embedCache, rt.digests)
+	}))
+
+	mux.HandleFunc("/v1/completions", rt.priority.Track(rt.completions))
+
+	mux.HandleFunc("/v1/messages", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		MessagesHandler(w, r, rt.config, rt.client, rt.usage)
+	}))
+
+	mux.HandleFunc("/v1beta/models/", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		GeminiHandler(w, r, rt.config, rt.client, rt.usage)
+	}))
+
+	// Azure OpenAI deployment routes
+	mux.HandleFunc("/openai/deployments/", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		AzureHandler(w, r, rt.config, rt.modelEndpoints())
+	}))
+
+	mux.HandleFunc("/v1/moderations", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		ModerationHandler(w, r, rt.config, rt.client, rt.usage)
+	}))
+
+	mux.HandleFunc("/v1/models", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
 		ModelsHandler(w, r, rt.config, rt.client)
-	})
+	}))
 
-	mux.HandleFunc("/v1/models/", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/v1/models/", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
 		ModelHandler(w, r, rt.config, rt.client)
-	})
+	}))
 
-	mux.HandleFunc("/v1/responses", func(w http.ResponseWriter, r *http.Request) {
-		ResponseHandler(w, r, rt.config, rt.client, rt.usage)
-	})
+	mux.HandleFunc("/v1/responses", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		ResponseHandler(w, r, rt.config, rt.client, rt.usage, rt.responses, rt.background, rt.vectors)
+	}))
+
+	mux.HandleFunc("/v1/responses/", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		ResponseItemHandler(w, r, rt.config, rt.responses, rt.background)
+	}))
+
+	// Native Ollama API, relayed with the same keys and usage accounting
+	mux.HandleFunc("/api/", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		NativeHandler(w, r, rt.config, rt.client, rt.usage)
+	}))
+
+	mux.HandleFunc("/v1/files", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		FileHandler(w, r, rt.config, rt.files)
+	}))
+
+	mux.HandleFunc("/v1/files/", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		FileHandler(w, r, rt.config, rt.files)
+	}))
+
+	mux.HandleFunc("/v1/vector_stores", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		VectorStoreHandler(w, r, rt.config, rt.usage, rt.vectors)
+	}))
+
+	mux.HandleFunc("/v1/vector_stores/", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		VectorStoreHandler(w, r, rt.config, rt.usage, rt.vectors)
+	}))
+
+	mux.HandleFunc("/v1/batches", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		BatchHandler(w, r, rt.config, rt.files, rt.batches)
+	}))
+
+	mux.HandleFunc("/v1/batches/", rt.priority.Track(func(w http.ResponseWriter, r *http.Request) {
+		BatchHandler(w, r, rt.config, rt.files, rt.batches)
+	}))
 
 	rt.logger.Info("Routes configured successfully")
 }
 
+// modelEndpoints returns the handlers reachable through batches and Azure deployments
+func (rt *Router) modelEndpoints() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/v1/chat/completions": rt.chat,
+		"/v1/embeddings":       rt.embeddings,
+		"/v1/completions":      rt.completions,
+	}
+}
+
+func (rt *Router) chat(w http.ResponseWriter, r *http.Request) {
+	ChatHandler(w, r, rt.config, rt.client, rt.usage)
+}
+
+func (rt *Router) embeddings(w http.ResponseWriter, r *http.Request) {
+	EmbeddingHandler(w, r, rt.config, rt.client, rt.usage, rt.embedCache, rt.digests, rt.vocabs)
+}
+
+func (rt *Router) completions(w http.ResponseWriter, r *http.Request) {
+	CompletionHandler(w, r, rt.config, rt.client, rt.usage, rt.vocabs)
+}
+
 // writeError writes an error response using the unified error package
 func writeError(w http.ResponseWriter, err *errors.APIError) {
 	errors.WriteError(w, err)
@@ -69,6 +193,11 @@ func writeError(w http.ResponseWriter, err *errors.APIError) {
 
 // getAliasFromRequest extracts the API key alias from the request
 func getAliasFromRequest(r *http.Request, cfg *config.Config) string {
+	// Requests run on behalf of a key, such as batch lines, carry the alias in their context
+	if alias := middleware.GetAliasFromContext(r.Context()); alias != "unknown" {
+		return alias
+	}
+
 	// Get Authorization header
 	authHeader := r.Header.Get("Authorization")
 	if len(authHeader) < 8 || authHeader[:7] != "Bearer " {
@@ -18,23 +18,39 @@ func UsageHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
 
 	stats := middleware.GetGlobalStats().GetStats()
 
+	type ModelStats struct {
+		PromptTokens     int64 `json:"prompt_tokens"`
+		CompletionTokens int64 `json:"completion_tokens"`
+		Requests         int64 `json:"requests"`
+	}
+
 	type AliasStats struct {
-		PromptTokens      int64 `json:"prompt_tokens"`
-		CompletionTokens  int64 `json:"completion_tokens"`
-		EmbeddingTokens   int64 `json:"embedding_tokens"`
-		TotalRequests     int64 `json:"total_requests"`
-		EmbeddingRequests int64 `json:"embedding_requests"`
+		PromptTokens      int64                 `json:"prompt_tokens"`
+		CompletionTokens  int64                 `json:"completion_tokens"`
+		EmbeddingTokens   int64                 `json:"embedding_tokens"`
+		TotalRequests     int64                 `json:"total_requests"`
+		EmbeddingRequests int64                 `json:"embedding_requests"`
+		Models            map[string]ModelStats `json:"models"` // Completion usage by serving model
 	}
 
 	result := make(map[string]AliasStats)
 
 	for alias, record := range stats {
+		models := make(map[string]ModelStats, len(record.Models))
+		for model, u := range record.Models {
+			models[model] = ModelStats{
+				PromptTokens:     u.PromptTokens,
+				CompletionTokens: u.CompletionTokens,
+				Requests:         u.Requests,
+			}
+		}
 		result[alias] = AliasStats{
 			PromptTokens:      record.PromptTokens,
 			CompletionTokens:  record.CompletionTokens,
 			EmbeddingTokens:   record.EmbeddingTokens,
 			TotalRequests:     record.TotalRequests,
 			EmbeddingRequests: record.EmbeddingRequests,
+			Models:            models,
 		}
 	}
 
@@ -0,0 +1,747 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ollama2openai/cache"
+	"ollama2openai/config"
+	"ollama2openai/filestore"
+	"ollama2openai/middleware"
+	"ollama2openai/openai"
+	"ollama2openai/ollama"
+	"ollama2openai/pkg/errors"
+	"ollama2openai/tokenizer"
+	"ollama2openai/vectorstore"
+)
+
+// VectorStores chunks and embeds documents into the local vector store index and searches it
+type VectorStores struct {
+	config   *config.Config
+	index    vectorstore.Index
+	files    filestore.Storage
+	embedder *embedder
+}
+
+// NewVectorStores creates the vector store service, embedding through the optional cache
+func NewVectorStores(cfg *config.Config, client ollama.ClientInterface, index vectorstore.Index, files filestore.Storage, embedCache cache.EmbeddingCache, digests *ModelDigests) *VectorStores {
+	return &VectorStores{
+		config: cfg,
+		index:  index,
+		files:  files,
+		embedder: &embedder{
+			client:    client,
+			batchSize: cfg.Embeddings.BatchSize,
+			cache:     embedCache,
+			digests:   digests,
+		},
+	}
+}
+
+// getStore loads a vector store, hiding stores owned by other aliases
+func (s *VectorStores) getStore(id, alias string) (*vectorstore.VectorStore, *errors.APIError) {
+	vs, err := s.index.GetStore(id)
+	if err != nil {
+		if stderrors.Is(err, vectorstore.ErrNotFound) {
+			return nil, errors.ErrNotFound.WithMessage(fmt.Sprintf("Vector store '%s' not found", id))
+		}
+		return nil, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to load vector store: %v", err))
+	}
+
+	if vs.Alias != alias {
+		return nil, errors.ErrNotFound.WithMessage(fmt.Sprintf("Vector store '%s' not found", id))
+	}
+
+	return vs, nil
+}
+
+// addDocument splits text into token windows, embeds them and stores them as one file
+// It returns the stored file and the prompt tokens used
+func (s *VectorStores) addDocument(ctx context.Context, vs *vectorstore.VectorStore, file *vectorstore.File, text string, chunkSize, overlap int) (*vectorstore.File, int, error) {
+	windows := tokenizer.SplitWindows(text, chunkSize, overlap)
+
+	texts := make([]string, len(windows))
+	for i, w := range windows {
+		texts[i] = w.Text
+	}
+
+	embeddings, tokens, err := s.embedder.embed(ctx, vs.Model, texts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	chunks := make([]vectorstore.Chunk, len(texts))
+	for i, emb := range embeddings {
+		chunks[i] = vectorstore.NewChunk(texts[i], emb)
+	}
+
+	file.StoreID = vs.ID
+	file.UsageBytes = int64(len(text))
+	file.ChunkCount = len(chunks)
+	if err := s.index.AddFile(file, chunks); err != nil {
+		return nil, 0, err
+	}
+	return file, tokens, nil
+}
+
+// uploadedDocument loads an uploaded file owned by alias as a vector store document
+// Only UTF-8 text files can be indexed
+func (s *VectorStores) uploadedDocument(id, alias string, attributes map[string]interface{}) (*vectorstore.File, string, *errors.APIError) {
+	upload, apiErr := getUploadedFile(s.files, id, alias)
+	if apiErr != nil {
+		return nil, "", errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("File '%s' not found", id))
+	}
+
+	content, err := s.files.Open(upload.ID)
+	if err != nil {
+		return nil, "", errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to open file: %v", err))
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, "", errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to read file: %v", err))
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return nil, "", errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("File '%s' is not a text document", id))
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, "", errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("File '%s' is empty", id))
+	}
+
+	file := &vectorstore.File{
+		ID:         upload.ID,
+		Filename:   upload.Filename,
+		Attributes: attributes,
+		CreatedAt:  time.Now(),
+	}
+	return file, string(data), nil
+}
+
+// search embeds query once per embedding model used by the stores and returns
+// the best matching chunks across all of them
+func (s *VectorStores) search(ctx context.Context, stores []*vectorstore.VectorStore, query string, limit int) ([]vectorstore.SearchResult, int, error) {
+	byModel := make(map[string][]string)
+	var models []string
+	for _, vs := range stores {
+		if _, ok := byModel[vs.Model]; !ok {
+			models = append(models, vs.Model)
+		}
+		byModel[vs.Model] = append(byModel[vs.Model], vs.ID)
+	}
+
+	var results []vectorstore.SearchResult
+	totalTokens := 0
+
+	for _, model := range models {
+		embeddings, tokens, err := s.embedder.embed(ctx, model, []string{query})
+		if err != nil {
+			return nil, 0, err
+		}
+		totalTokens += tokens
+
+		found, err := s.index.Search(byModel[model], embeddings[0], limit)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, found...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, totalTokens, nil
+}
+
+// chunkSizes resolves a chunking strategy to a chunk size and overlap in tokens
+func (s *VectorStores) chunkSizes(strategy *openai.ChunkingStrategy) (int, int, *errors.APIError) {
+	if strategy == nil || strategy.Type == "" || strategy.Type == "auto" {
+		return s.config.VectorStores.ChunkSize, s.config.VectorStores.ChunkOverlap, nil
+	}
+	if strategy.Type != "static" || strategy.Static == nil {
+		return 0, 0, errors.ErrInvalidRequest.WithMessage("chunking_strategy must be 'auto' or 'static' with a 'static' object")
+	}
+
+	size, overlap := strategy.Static.MaxChunkSizeTokens, strategy.Static.ChunkOverlapTokens
+	if size <= 0 {
+		return 0, 0, errors.ErrInvalidRequest.WithMessage("max_chunk_size_tokens must be a positive integer")
+	}
+	if overlap < 0 || overlap > size/2 {
+		return 0, 0, errors.ErrInvalidRequest.WithMessage("chunk_overlap_tokens must be between 0 and half of max_chunk_size_tokens")
+	}
+	return size, overlap, nil
+}
+
+// VectorStoreHandler handles the vector stores API
+// Path format: /v1/vector_stores, /v1/vector_stores/{id}, /v1/vector_stores/{id}/files,
+// /v1/vector_stores/{id}/files/{file_id} or /v1/vector_stores/{id}/search
+func VectorStoreHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, usage middleware.UsageTracker, stores *VectorStores) {
+	parts := splitPath(r.URL.Path)
+	alias := getAliasFromRequest(r, cfg)
+
+	if len(parts) == 2 {
+		switch r.Method {
+		case http.MethodPost:
+			createVectorStore(w, r, usage, stores, alias)
+		case http.MethodGet:
+			listVectorStores(w, r, stores, alias)
+		default:
+			writeError(w, errors.ErrMethodNotAllowed)
+		}
+		return
+	}
+
+	if len(parts) < 3 || len(parts) > 5 {
+		writeError(w, errors.ErrNotFound)
+		return
+	}
+
+	vs, apiErr := stores.getStore(parts[2], alias)
+
+	switch {
+	case len(parts) == 3:
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeVectorStore(w, stores, vs)
+		case http.MethodPost:
+			modifyVectorStore(w, r, stores, vs)
+		case http.MethodDelete:
+			if err := stores.index.DeleteStore(vs.ID); err != nil {
+				writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to delete vector store: %v", err)))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(openai.DeletedObject{
+				ID:      vs.ID,
+				Object:  "vector_store.deleted",
+				Deleted: true,
+			})
+		default:
+			writeError(w, errors.ErrMethodNotAllowed)
+		}
+
+	case parts[3] == "search" && len(parts) == 4:
+		if r.Method != http.MethodPost {
+			writeError(w, errors.ErrMethodNotAllowed)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+		searchVectorStore(w, r, usage, stores, vs, alias)
+
+	case parts[3] == "files" && len(parts) == 4:
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			addVectorStoreFile(w, r, usage, stores, vs, alias)
+		case http.MethodGet:
+			listVectorStoreFiles(w, r, stores, vs)
+		default:
+			writeError(w, errors.ErrMethodNotAllowed)
+		}
+
+	case parts[3] == "files" && len(parts) == 5:
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+		file, err := stores.index.GetFile(vs.ID, parts[4])
+		if err != nil {
+			writeError(w, errors.ErrNotFound.WithMessage(fmt.Sprintf("File '%s' not found in vector store '%s'", parts[4], vs.ID)))
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(toOpenAIVectorStoreFile(file))
+		case http.MethodDelete:
+			if err := stores.index.DeleteFile(vs.ID, file.ID); err != nil {
+				writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to delete file: %v", err)))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(openai.DeletedObject{
+				ID:      file.ID,
+				Object:  "vector_store.file.deleted",
+				Deleted: true,
+			})
+		default:
+			writeError(w, errors.ErrMethodNotAllowed)
+		}
+
+	default:
+		writeError(w, errors.ErrNotFound)
+	}
+}
+
+func createVectorStore(w http.ResponseWriter, r *http.Request, usage middleware.UsageTracker, stores *VectorStores, alias string) {
+	var req openai.VectorStoreRequest
+	if apiErr := decodeRequestBody(r, &req); apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	chunkSize, overlap, apiErr := stores.chunkSizes(req.ChunkingStrategy)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	// Load every file up front so a bad file_id does not leave a half-built store
+	documents := make([]*vectorstore.File, len(req.FileIDs))
+	texts := make([]string, len(req.FileIDs))
+	for i, id := range req.FileIDs {
+		documents[i], texts[i], apiErr = stores.uploadedDocument(id, alias, nil)
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+	}
+
+	vs := &vectorstore.VectorStore{
+		ID:        fmt.Sprintf("vs_%s", generateID()),
+		Alias:     alias,
+		Model:     req.Model,
+		Metadata:  req.Metadata,
+		CreatedAt: time.Now(),
+	}
+	if req.Name != nil {
+		vs.Name = *req.Name
+	}
+	if vs.Model == "" {
+		vs.Model = stores.config.VectorStores.EmbeddingModel
+	}
+
+	if err := stores.index.SaveStore(vs); err != nil {
+		writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to create vector store: %v", err)))
+		return
+	}
+
+	if len(documents) > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), stores.config.GetTimeout())
+		defer cancel()
+
+		totalTokens := 0
+		for i, file := range documents {
+			_, tokens, err := stores.addDocument(ctx, vs, file, texts[i], chunkSize, overlap)
+			if err != nil {
+				stores.index.DeleteStore(vs.ID)
+				writeError(w, errors.FromUpstream(err, fmt.Sprintf("Failed to index file '%s': %v", file.ID, err)))
+				return
+			}
+			totalTokens += tokens
+		}
+		usage.RecordEmbedding(alias, int64(totalTokens))
+	}
+
+	writeVectorStore(w, stores, vs)
+}
+
+func modifyVectorStore(w http.ResponseWriter, r *http.Request, stores *VectorStores, vs *vectorstore.VectorStore) {
+	var req openai.VectorStoreRequest
+	if apiErr := decodeRequestBody(r, &req); apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	if req.Model != "" && req.Model != vs.Model {
+		writeError(w, errors.ErrInvalidRequest.WithMessage("The embedding model of a vector store cannot be changed"))
+		return
+	}
+	if req.Name != nil {
+		vs.Name = *req.Name
+	}
+	if req.Metadata != nil {
+		vs.Metadata = req.Metadata
+	}
+
+	if err := stores.index.SaveStore(vs); err != nil {
+		writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to update vector store: %v", err)))
+		return
+	}
+
+	writeVectorStore(w, stores, vs)
+}
+
+func listVectorStores(w http.ResponseWriter, r *http.Request, stores *VectorStores, alias string) {
+	all, err := stores.index.ListStores(alias)
+	if err != nil {
+		writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to list vector stores: %v", err)))
+		return
+	}
+
+	page, hasMore, apiErr := paginate(r, all, func(vs *vectorstore.VectorStore) string { return vs.ID })
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	list := openai.VectorStoreList{
+		Object:  "list",
+		Data:    make([]openai.VectorStore, 0, len(page)),
+		HasMore: hasMore,
+	}
+	for _, vs := range page {
+		list.Data = append(list.Data, toOpenAIVectorStore(stores, vs))
+	}
+	if len(list.Data) > 0 {
+		list.FirstID = list.Data[0].ID
+		list.LastID = list.Data[len(list.Data)-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+func addVectorStoreFile(w http.ResponseWriter, r *http.Request, usage middleware.UsageTracker, stores *VectorStores, vs *vectorstore.VectorStore, alias string) {
+	var req openai.VectorStoreFileRequest
+	if apiErr := decodeRequestBody(r, &req); apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	chunkSize, overlap, apiErr := stores.chunkSizes(req.ChunkingStrategy)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	var file *vectorstore.File
+	text := req.Text
+	switch {
+	case req.FileID != "":
+		file, text, apiErr = stores.uploadedDocument(req.FileID, alias, req.Attributes)
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+	case strings.TrimSpace(req.Text) != "":
+		file = &vectorstore.File{
+			ID:         fmt.Sprintf("file-%s", generateID()),
+			Filename:   req.Filename,
+			Attributes: req.Attributes,
+			CreatedAt:  time.Now(),
+		}
+	default:
+		writeError(w, errors.ErrInvalidRequest.WithMessage("Either 'file_id' or 'text' is required"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), stores.config.GetTimeout())
+	defer cancel()
+
+	file, tokens, err := stores.addDocument(ctx, vs, file, text, chunkSize, overlap)
+	if err != nil {
+		writeError(w, errors.FromUpstream(err, fmt.Sprintf("Failed to index document: %v", err)))
+		return
+	}
+	usage.RecordEmbedding(alias, int64(tokens))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toOpenAIVectorStoreFile(file))
+}
+
+func listVectorStoreFiles(w http.ResponseWriter, r *http.Request, stores *VectorStores, vs *vectorstore.VectorStore) {
+	files, err := stores.index.ListFiles(vs.ID)
+	if err != nil {
+		writeError(w, errors.ErrInternalServer.WithMessage(fmt.Sprintf("Failed to list files: %v", err)))
+		return
+	}
+
+	page, hasMore, apiErr := paginate(r, files, func(f *vectorstore.File) string { return f.ID })
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	list := openai.VectorStoreFileList{
+		Object:  "list",
+		Data:    make([]openai.VectorStoreFile, 0, len(page)),
+		HasMore: hasMore,
+	}
+	for _, f := range page {
+		list.Data = append(list.Data, toOpenAIVectorStoreFile(f))
+	}
+	if len(list.Data) > 0 {
+		list.FirstID = list.Data[0].ID
+		list.LastID = list.Data[len(list.Data)-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+func searchVectorStore(w http.ResponseWriter, r *http.Request, usage middleware.UsageTracker, stores *VectorStores, vs *vectorstore.VectorStore, alias string) {
+	var req openai.VectorStoreSearchRequest
+	if apiErr := decodeRequestBody(r, &req); apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	var queries []string
+	switch q := req.Query.(type) {
+	case string:
+		queries = []string{q}
+	case []interface{}:
+		for _, item := range q {
+			if s, ok := item.(string); ok {
+				queries = append(queries, s)
+			}
+		}
+	}
+	query := strings.TrimSpace(strings.Join(queries, "\n"))
+	if query == "" {
+		writeError(w, errors.ErrInvalidRequest.WithMessage("'query' must be a non-empty string or array of strings"))
+		return
+	}
+
+	limit := stores.config.VectorStores.MaxResults
+	if req.MaxNumResults != nil {
+		if *req.MaxNumResults < 1 || *req.MaxNumResults > 50 {
+			writeError(w, errors.ErrInvalidRequest.WithMessage("max_num_results must be between 1 and 50"))
+			return
+		}
+		limit = *req.MaxNumResults
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), stores.config.GetTimeout())
+	defer cancel()
+
+	results, tokens, err := stores.search(ctx, []*vectorstore.VectorStore{vs}, query, limit)
+	if err != nil {
+		writeError(w, errors.FromUpstream(err, fmt.Sprintf("Ollama error: %v", err)))
+		return
+	}
+	usage.RecordEmbedding(alias, int64(tokens))
+
+	page := openai.VectorStoreSearchPage{
+		Object:      "vector_store.search_results.page",
+		SearchQuery: queries,
+		Data:        make([]openai.VectorStoreSearchResult, 0, len(results)),
+	}
+	for _, res := range results {
+		page.Data = append(page.Data, openai.VectorStoreSearchResult{
+			FileID:     res.File.ID,
+			Filename:   res.File.Filename,
+			Score:      res.Score,
+			Attributes: res.File.Attributes,
+			Content:    []openai.ContentPart{{Type: "text", Text: res.Text}},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// fileSearch runs the file_search tool of a Response API request against the latest
+// user message, returning the file_search_call output item, a system message carrying
+// the retrieved chunks and the prompt tokens used
+// It returns a nil item when the request has no file_search tool or no user text
+func fileSearch(ctx context.Context, stores *VectorStores, alias string, tools []openai.ResponseTool, messages []openai.ChatMessage, includeResults bool) (*openai.OutputItem, *openai.ChatMessage, int, *errors.APIError) {
+	var vectorStores []*vectorstore.VectorStore
+	seen := make(map[string]bool)
+	limit := 0
+	for _, tool := range tools {
+		if tool.Type != "file_search" {
+			continue
+		}
+		if len(tool.VectorStoreIDs) == 0 {
+			return nil, nil, 0, errors.ErrInvalidRequest.WithMessage("file_search requires at least one vector store id")
+		}
+		for _, id := range tool.VectorStoreIDs {
+			if seen[id] {
+				continue
+			}
+			vs, apiErr := stores.getStore(id, alias)
+			if apiErr != nil {
+				return nil, nil, 0, errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Vector store '%s' not found", id))
+			}
+			seen[id] = true
+			vectorStores = append(vectorStores, vs)
+		}
+		if tool.MaxNumResults != nil && *tool.MaxNumResults > 0 {
+			limit = max(limit, *tool.MaxNumResults)
+		}
+	}
+	if len(vectorStores) == 0 {
+		return nil, nil, 0, nil
+	}
+	if limit == 0 {
+		limit = stores.config.VectorStores.MaxResults
+	}
+
+	query := ""
+	for i := len(messages) - 1; i >= 0 && query == ""; i-- {
+		if messages[i].Role != "user" {
+			continue
+		}
+		switch content := messages[i].Content.(type) {
+		case string:
+			query = content
+		case []interface{}:
+			query = buildContentFromParts(content).text
+		}
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil, 0, nil
+	}
+
+	results, tokens, err := stores.search(ctx, vectorStores, query, limit)
+	if err != nil {
+		return nil, nil, 0, errors.FromUpstream(err, fmt.Sprintf("file_search failed: %v", err))
+	}
+
+	item := &openai.OutputItem{
+		ID:      fmt.Sprintf("fs_%s", generateID()),
+		Type:    "file_search_call",
+		Status:  "completed",
+		Queries: []string{query},
+	}
+	if includeResults {
+		item.Results = make([]openai.FileSearchResult, 0, len(results))
+		for _, res := range results {
+			item.Results = append(item.Results, openai.FileSearchResult{
+				FileID:     res.File.ID,
+				Filename:   res.File.Filename,
+				Score:      res.Score,
+				Text:       res.Text,
+				Attributes: res.File.Attributes,
+			})
+		}
+	}
+
+	if len(results) == 0 {
+		return item, nil, tokens, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("The following excerpts were retrieved from the user's files. Use them to answer when they are relevant, and mention the file they came from.\n")
+	for _, res := range results {
+		name := res.File.Filename
+		if name == "" {
+			name = res.File.ID
+		}
+		fmt.Fprintf(&sb, "\n<file name=%q>\n%s\n</file>\n", name, res.Text)
+	}
+
+	return item, &openai.ChatMessage{Role: "system", Content: sb.String()}, tokens, nil
+}
+
+func writeVectorStore(w http.ResponseWriter, stores *VectorStores, vs *vectorstore.VectorStore) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toOpenAIVectorStore(stores, vs))
+}
+
+// toOpenAIVectorStore converts a stored vector store, counting its files
+// Documents are indexed synchronously, so every stored file is completed
+func toOpenAIVectorStore(stores *VectorStores, vs *vectorstore.VectorStore) openai.VectorStore {
+	result := openai.VectorStore{
+		ID:        vs.ID,
+		Object:    "vector_store",
+		CreatedAt: vs.CreatedAt.Unix(),
+		Name:      vs.Name,
+		Status:    "completed",
+		Metadata:  vs.Metadata,
+	}
+	if result.Metadata == nil {
+		result.Metadata = map[string]string{}
+	}
+
+	files, _ := stores.index.ListFiles(vs.ID)
+	for _, f := range files {
+		result.UsageBytes += f.UsageBytes
+	}
+	result.FileCounts.Completed = len(files)
+	result.FileCounts.Total = len(files)
+	return result
+}
+
+func toOpenAIVectorStoreFile(f *vectorstore.File) openai.VectorStoreFile {
+	return openai.VectorStoreFile{
+		ID:            f.ID,
+		Object:        "vector_store.file",
+		CreatedAt:     f.CreatedAt.Unix(),
+		VectorStoreID: f.StoreID,
+		Status:        "completed",
+		UsageBytes:    f.UsageBytes,
+		Attributes:    f.Attributes,
+	}
+}
+
+// decodeRequestBody reads a JSON request body into v
+func decodeRequestBody(r *http.Request, v interface{}) *errors.APIError {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return errors.ErrInvalidRequest.WithMessage("Failed to read request body")
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return errors.ErrInvalidRequest.WithMessage(fmt.Sprintf("Invalid request body: %v", err))
+	}
+	return nil
+}
+
+// paginate applies the limit, order and after query parameters to items sorted oldest first
+func paginate[T any](r *http.Request, items []T, id func(T) string) ([]T, bool, *errors.APIError) {
+	query := r.URL.Query()
+
+	limit := 20
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			return nil, false, errors.ErrInvalidRequest.WithMessage("limit must be between 1 and 100")
+		}
+		limit = n
+	}
+
+	switch query.Get("order") {
+	case "", "desc":
+		reversed := make([]T, len(items))
+		for i, item := range items {
+			reversed[len(items)-1-i] = item
+		}
+		items = reversed
+	case "asc":
+	default:
+		return nil, false, errors.ErrInvalidRequest.WithMessage("order must be 'asc' or 'desc'")
+	}
+
+	if after := query.Get("after"); after != "" {
+		for i, item := range items {
+			if id(item) == after {
+				items = items[i+1:]
+				break
+			}
+		}
+	}
+
+	if len(items) > limit {
+		return items[:limit], true, nil
+	}
+	return items, false, nil
+}
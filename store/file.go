@@ -0,0 +1,158 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"ollama2openai/pkg/fsutil"
+)
+
+// filePruneInterval is how often saving a response also removes expired ones,
+// since that reads every file in the directory
+const filePruneInterval = time.Minute
+
+// FileResponseStore keeps responses as JSON files in a local directory
+type FileResponseStore struct {
+	mu        sync.Mutex
+	dir       string
+	ttl       time.Duration
+	lastPrune time.Time
+}
+
+// NewFileResponseStore creates a file-backed response store, removing expired entries
+func NewFileResponseStore(dir string, ttl time.Duration) (*FileResponseStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create response store directory: %w", err)
+	}
+
+	s := &FileResponseStore{dir: dir, ttl: ttl}
+	s.prune()
+	return s, nil
+}
+
+// Save writes a response to disk
+func (s *FileResponseStore) Save(resp *StoredResponse) error {
+	path, err := s.path(resp.Response.ID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.lastPrune) >= filePruneInterval {
+		s.prune()
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Get reads a response from disk
+func (s *FileResponseStore) Get(id string) (*StoredResponse, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp, err := readStoredResponse(path)
+	if err != nil {
+		return nil, err
+	}
+	if expired(resp.CreatedAt, s.ttl) {
+		os.Remove(path)
+		return nil, ErrNotFound
+	}
+	return resp, nil
+}
+
+// Delete removes a response from disk
+func (s *FileResponseStore) Delete(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete response: %w", err)
+	}
+	return nil
+}
+
+// prune removes expired responses from the directory; s.mu must be held once
+// the store is shared
+func (s *FileResponseStore) prune() {
+	if s.ttl <= 0 {
+		return
+	}
+	s.lastPrune = time.Now()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(s.dir, e.Name())
+		resp, err := readStoredResponse(path)
+		if err != nil || expired(resp.CreatedAt, s.ttl) {
+			os.Remove(path)
+		}
+	}
+}
+
+func (s *FileResponseStore) path(id string) (string, error) {
+	if !fsutil.ValidID(id) {
+		return "", fmt.Errorf("invalid id: %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+func readStoredResponse(path string) (*StoredResponse, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var resp StoredResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &resp, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to rename file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"ollama2openai/openai"
+)
+
+// ErrNotFound is returned when a stored item does not exist or has expired
+var ErrNotFound = errors.New("not found")
+
+// StoredResponse is a Response API result kept for later retrieval
+// and for rebuilding the conversation via previous_response_id
+type StoredResponse struct {
+	Response   openai.ResponseResponse `json:"response"`
+	Alias      string                  `json:"alias"`
+	InputItems []openai.InputItem      `json:"input_items"`
+	Messages   []openai.ChatMessage    `json:"messages"` // Full conversation including the output
+	CreatedAt  time.Time               `json:"created_at"`
+}
+
+// ResponseStore defines the interface for persisting Response API results
+// This allows for different implementations (in-memory, file, database, etc.)
+type ResponseStore interface {
+	// Save stores a response, replacing any existing one with the same ID
+	Save(resp *StoredResponse) error
+
+	// Get returns the response with the given ID, or ErrNotFound
+	Get(id string) (*StoredResponse, error)
+
+	// Delete removes the response with the given ID, or returns ErrNotFound
+	Delete(id string) error
+}
+
+// Ensure implementations satisfy ResponseStore
+var (
+	_ ResponseStore = (*MemoryResponseStore)(nil)
+	_ ResponseStore = (*FileResponseStore)(nil)
+)
+
+// expired reports whether an item created at t is older than ttl
+// A zero ttl means items never expire
+func expired(t time.Time, ttl time.Duration) bool {
+	return ttl > 0 && time.Since(t) > ttl
+}
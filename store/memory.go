@@ -0,0 +1,67 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryResponseStore keeps responses in memory with an optional TTL
+type MemoryResponseStore struct {
+	mu        sync.RWMutex
+	responses map[string]*StoredResponse
+	ttl       time.Duration
+}
+
+// NewMemoryResponseStore creates a new in-memory response store
+func NewMemoryResponseStore(ttl time.Duration) *MemoryResponseStore {
+	return &MemoryResponseStore{
+		responses: make(map[string]*StoredResponse),
+		ttl:       ttl,
+	}
+}
+
+// Save stores a response and drops any expired entries
+func (s *MemoryResponseStore) Save(resp *StoredResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, r := range s.responses {
+		if expired(r.CreatedAt, s.ttl) {
+			delete(s.responses, id)
+		}
+	}
+
+	// Keep a copy so later changes by the caller are not visible until saved again
+	copied := *resp
+	s.responses[resp.Response.ID] = &copied
+	return nil
+}
+
+// Get returns a stored response
+func (s *MemoryResponseStore) Get(id string) (*StoredResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resp, ok := s.responses[id]
+	if !ok || expired(resp.CreatedAt, s.ttl) {
+		return nil, ErrNotFound
+	}
+	return resp, nil
+}
+
+// Delete removes a stored response
+func (s *MemoryResponseStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp, ok := s.responses[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.responses, id)
+
+	if expired(resp.CreatedAt, s.ttl) {
+		return ErrNotFound
+	}
+	return nil
+}
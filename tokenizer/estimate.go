@@ -6,6 +6,13 @@ import (
 	"unicode/utf8"
 )
 
+// Script detection patterns (RE2 uses \x{...} rather than \u escapes)
+var (
+	chinesePattern  = regexp.MustCompile(`[\x{4e00}-\x{9fa5}]`)
+	japanesePattern = regexp.MustCompile(`[\x{3040}-\x{309f}\x{30a0}-\x{30ff}]`)
+	koreanPattern   = regexp.MustCompile(`[\x{ac00}-\x{d7af}]`)
+)
+
 // EstimateTokenCount estimates the number of tokens in a text string
 // This is a simplified estimation based on English text patterns
 // A more accurate count would require a proper tokenizer like tiktoken
@@ -38,9 +45,9 @@ func EstimateTokenCount(text string) int {
 		strings.Contains(text, "}")
 
 	// Adjust for non-English content
-	isChinese := regexp.MustCompile(`[\u4e00-\u9fa5]`).MatchString(text)
-	isJapanese := regexp.MustCompile(`[\u3040-\u309f\u30a0-\u30ff]`).MatchString(text)
-	isKorean := regexp.MustCompile(`[\uac00-\ud7af]`).MatchString(text)
+	isChinese := chinesePattern.MatchString(text)
+	isJapanese := japanesePattern.MatchString(text)
+	isKorean := koreanPattern.MatchString(text)
 
 	if isCode {
 		// Code tends to have more tokens per character
@@ -0,0 +1,151 @@
+package tokenizer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Window is a slice of text produced by SplitWindows
+type Window struct {
+	Text   string
+	Start  int // Byte offset of the window in the original text
+	End    int // Byte offset just past the window
+	Tokens int // Estimated token count
+}
+
+// SplitWindows splits text into windows of at most maxTokens estimated tokens,
+// each overlapping the previous one by about overlap tokens
+// Windows break between words, or between characters for CJK text; runs without
+// spaces longer than a window, such as base64 or minified JSON, are cut by length
+// Text that already fits is returned as a single window
+func SplitWindows(text string, maxTokens, overlap int) []Window {
+	total := EstimateTokenCount(text)
+	if maxTokens <= 0 || total <= maxTokens {
+		return []Window{{Text: text, Start: 0, End: len(text), Tokens: total}}
+	}
+	if overlap < 0 || overlap >= maxTokens {
+		overlap = 0
+	}
+
+	var pieces []piece
+	for _, p := range splitPieces(text) {
+		pieces = append(pieces, splitOversize(text, p, maxTokens)...)
+	}
+
+	var windows []Window
+	start := 0
+	for start < len(pieces) {
+		// Grow the window until the next piece would exceed the budget
+		end := start
+		tokens := 0
+		for end < len(pieces) {
+			if tokens+pieces[end].tokens > maxTokens && end > start {
+				break
+			}
+			tokens += pieces[end].tokens
+			end++
+		}
+
+		windows = append(windows, Window{
+			Text:   text[pieces[start].start:pieces[end-1].end],
+			Start:  pieces[start].start,
+			End:    pieces[end-1].end,
+			Tokens: tokens,
+		})
+
+		if end == len(pieces) {
+			break
+		}
+
+		// Step back far enough to repeat about overlap tokens, always moving forward
+		next := end
+		carried := 0
+		for next > start+1 && carried+pieces[next-1].tokens <= overlap {
+			next--
+			carried += pieces[next].tokens
+		}
+		start = next
+	}
+
+	return windows
+}
+
+type piece struct {
+	start  int
+	end    int
+	tokens int
+}
+
+// splitPieces splits text into words with their trailing whitespace, treating
+// each CJK character as its own piece
+func splitPieces(text string) []piece {
+	var pieces []piece
+	start := 0
+	inSpace := false
+
+	flush := func(end int) {
+		if end > start {
+			pieces = append(pieces, piece{
+				start:  start,
+				end:    end,
+				tokens: max(1, EstimateTokenCount(text[start:end])),
+			})
+		}
+		start = end
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		switch {
+		case isCJK(r):
+			flush(i)
+			flush(i + size)
+			inSpace = false
+		case unicode.IsSpace(r):
+			inSpace = true
+		default:
+			if inSpace {
+				flush(i)
+				inSpace = false
+			}
+		}
+		i += size
+	}
+	flush(len(text))
+
+	return pieces
+}
+
+// splitOversize cuts a piece estimated above maxTokens into runs of runes sized
+// in proportion to maxTokens, cutting further any run still estimated above it
+func splitOversize(text string, p piece, maxTokens int) []piece {
+	runes := utf8.RuneCountInString(text[p.start:p.end])
+	if p.tokens <= maxTokens || runes < 2 {
+		return []piece{p}
+	}
+
+	parts := max(2, (p.tokens+maxTokens-1)/maxTokens)
+	perPart := (runes + parts - 1) / parts
+
+	var pieces []piece
+	start, count := p.start, 0
+	for i := p.start; i < p.end; {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		count++
+		if count == perPart || i == p.end {
+			part := piece{start: start, end: i, tokens: max(1, EstimateTokenCount(text[start:i]))}
+			pieces = append(pieces, splitOversize(text, part, maxTokens)...)
+			start, count = i, 0
+		}
+	}
+	return pieces
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
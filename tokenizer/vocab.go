@@ -0,0 +1,281 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"ollama2openai/ollama"
+)
+
+// ErrNoVocabulary is returned when no tokenizer file is configured for a model
+var ErrNoVocabulary = errors.New("no tokenizer vocabulary configured")
+
+// decodeStyle describes how token strings are turned back into text
+type decodeStyle int
+
+const (
+	styleBytes     decodeStyle = iota // Tokens map directly to bytes (byte-level BPE, tiktoken)
+	styleMetaspace                    // SentencePiece: "▁" marks a space, <0xNN> marks a raw byte
+	styleWordPiece                    // BERT: "##" marks a continuation, other tokens are space separated
+)
+
+// Vocabulary maps token IDs back to text
+type Vocabulary struct {
+	style   decodeStyle
+	tokens  map[int]string
+	special map[int]bool
+}
+
+// LoadVocabulary reads a Hugging Face tokenizer.json or a tiktoken rank file
+// (one "<base64 token> <id>" pair per line)
+func LoadVocabulary(path string) (*Vocabulary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokenizer file: %w", err)
+	}
+
+	if strings.HasSuffix(path, ".json") {
+		return parseHFTokenizer(data)
+	}
+	return parseTiktoken(data)
+}
+
+// Decode converts token IDs to text, skipping special tokens
+func (v *Vocabulary) Decode(ids []int) (string, error) {
+	var buf bytes.Buffer
+
+	for i, id := range ids {
+		token, ok := v.tokens[id]
+		if !ok {
+			return "", fmt.Errorf("token id %d is not in the vocabulary", id)
+		}
+		if v.special[id] {
+			continue
+		}
+
+		switch v.style {
+		case styleBytes:
+			buf.WriteString(token)
+		case styleMetaspace:
+			buf.WriteString(decodeMetaspace(token))
+		case styleWordPiece:
+			if rest, ok := strings.CutPrefix(token, "##"); ok {
+				buf.WriteString(rest)
+			} else {
+				if i > 0 && buf.Len() > 0 {
+					buf.WriteByte(' ')
+				}
+				buf.WriteString(token)
+			}
+		}
+	}
+
+	text := buf.String()
+	if v.style == styleMetaspace {
+		text = strings.TrimPrefix(text, " ")
+	}
+	return text, nil
+}
+
+type hfTokenizer struct {
+	Model struct {
+		Type  string          `json:"type"`
+		Vocab json.RawMessage `json:"vocab"`
+	} `json:"model"`
+	AddedTokens []struct {
+		ID      int    `json:"id"`
+		Content string `json:"content"`
+		Special bool   `json:"special"`
+	} `json:"added_tokens"`
+}
+
+func parseHFTokenizer(data []byte) (*Vocabulary, error) {
+	var hf hfTokenizer
+	if err := json.Unmarshal(data, &hf); err != nil {
+		return nil, fmt.Errorf("failed to parse tokenizer.json: %w", err)
+	}
+
+	v := &Vocabulary{
+		tokens:  make(map[int]string),
+		special: make(map[int]bool),
+	}
+
+	// BPE and WordPiece store a token -> id map, Unigram a list of [token, score] pairs
+	var vocabMap map[string]int
+	var vocabList [][]interface{}
+	if err := json.Unmarshal(hf.Model.Vocab, &vocabMap); err == nil {
+		for token, id := range vocabMap {
+			v.tokens[id] = token
+		}
+	} else if err := json.Unmarshal(hf.Model.Vocab, &vocabList); err == nil {
+		for id, entry := range vocabList {
+			if len(entry) > 0 {
+				if token, ok := entry[0].(string); ok {
+					v.tokens[id] = token
+				}
+			}
+		}
+	} else {
+		return nil, fmt.Errorf("unsupported vocabulary format in tokenizer.json")
+	}
+
+	for _, t := range hf.AddedTokens {
+		v.tokens[t.ID] = t.Content
+		v.special[t.ID] = t.Special
+	}
+
+	switch {
+	case hf.Model.Type == "WordPiece":
+		v.style = styleWordPiece
+	case hasTokenContaining(v.tokens, "▁"):
+		v.style = styleMetaspace
+	default:
+		// Byte-level BPE writes bytes as printable runes, map them back
+		v.style = styleBytes
+		for id, token := range v.tokens {
+			if !v.special[id] {
+				v.tokens[id] = decodeByteLevel(token)
+			}
+		}
+	}
+
+	return v, nil
+}
+
+func parseTiktoken(data []byte) (*Vocabulary, error) {
+	v := &Vocabulary{
+		style:   styleBytes,
+		tokens:  make(map[int]string),
+		special: make(map[int]bool),
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid tiktoken line: %q", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid tiktoken token %q: %w", fields[0], err)
+		}
+		id, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid tiktoken rank %q: %w", fields[1], err)
+		}
+		v.tokens[id] = string(token)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tiktoken file: %w", err)
+	}
+
+	return v, nil
+}
+
+func hasTokenContaining(tokens map[int]string, s string) bool {
+	for _, token := range tokens {
+		if strings.Contains(token, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeMetaspace converts a SentencePiece token to text
+func decodeMetaspace(token string) string {
+	// Byte fallback tokens look like <0x0A>
+	if len(token) == 6 && strings.HasPrefix(token, "<0x") && strings.HasSuffix(token, ">") {
+		if b, err := strconv.ParseUint(token[3:5], 16, 8); err == nil {
+			return string([]byte{byte(b)})
+		}
+	}
+	return strings.ReplaceAll(token, "▁", " ")
+}
+
+var (
+	byteLevelOnce    sync.Once
+	byteLevelDecoder map[rune]byte
+)
+
+// decodeByteLevel reverses GPT-2's byte-to-unicode mapping
+func decodeByteLevel(token string) string {
+	byteLevelOnce.Do(func() {
+		byteLevelDecoder = make(map[rune]byte, 256)
+		n := 0
+		for b := 0; b < 256; b++ {
+			printable := (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF)
+			if printable {
+				byteLevelDecoder[rune(b)] = byte(b)
+			} else {
+				byteLevelDecoder[rune(256+n)] = byte(b)
+				n++
+			}
+		}
+	})
+
+	out := make([]byte, 0, len(token))
+	for _, r := range token {
+		if b, ok := byteLevelDecoder[r]; ok {
+			out = append(out, b)
+		} else {
+			out = append(out, string(r)...)
+		}
+	}
+	return string(out)
+}
+
+// Registry loads vocabularies on first use for the models that have a tokenizer file configured
+type Registry struct {
+	paths map[string]string // Normalized model name -> tokenizer file
+
+	mu     sync.Mutex
+	loaded map[string]*Vocabulary
+}
+
+// NewRegistry creates a registry from a model -> tokenizer file mapping
+func NewRegistry(paths map[string]string) *Registry {
+	normalized := make(map[string]string, len(paths))
+	for model, path := range paths {
+		normalized[ollama.NormalizeModelName(model)] = path
+	}
+	return &Registry{
+		paths:  normalized,
+		loaded: make(map[string]*Vocabulary),
+	}
+}
+
+// Get returns the vocabulary for model, or ErrNoVocabulary if none is configured
+func (r *Registry) Get(model string) (*Vocabulary, error) {
+	name := ollama.NormalizeModelName(model)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if v, ok := r.loaded[name]; ok {
+		return v, nil
+	}
+
+	path, ok := r.paths[name]
+	if !ok {
+		return nil, ErrNoVocabulary
+	}
+
+	v, err := LoadVocabulary(path)
+	if err != nil {
+		return nil, err
+	}
+	r.loaded[name] = v
+	return v, nil
+}
@@ -0,0 +1,321 @@
+package vectorstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"ollama2openai/pkg/fsutil"
+	"ollama2openai/pkg/vector"
+)
+
+// FlatIndex keeps every chunk in memory and searches by brute force, persisting
+// stores as JSON files under a local directory
+// Layout: <dir>/<store id>/store.json and <dir>/<store id>/files/<file id>.json
+type FlatIndex struct {
+	mu     sync.RWMutex
+	dir    string // Empty keeps stores in memory only
+	stores map[string]*storeEntry
+}
+
+type storeEntry struct {
+	store VectorStore
+	files map[string]*fileEntry
+}
+
+type fileEntry struct {
+	File   File    `json:"file"`
+	Chunks []Chunk `json:"chunks"`
+}
+
+// NewFlatIndex creates a flat index, loading any stores already saved in dir
+func NewFlatIndex(dir string) (*FlatIndex, error) {
+	idx := &FlatIndex{
+		dir:    dir,
+		stores: make(map[string]*storeEntry),
+	}
+	if dir == "" {
+		return idx, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create vector store directory: %w", err)
+	}
+	if err := idx.load(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// SaveStore creates a vector store or replaces its metadata
+func (idx *FlatIndex) SaveStore(vs *VectorStore) error {
+	if !fsutil.ValidID(vs.ID) {
+		return fmt.Errorf("invalid vector store id: %q", vs.ID)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.dir != "" {
+		if err := os.MkdirAll(filepath.Join(idx.dir, vs.ID, "files"), 0o755); err != nil {
+			return fmt.Errorf("failed to create vector store directory: %w", err)
+		}
+		if err := writeJSON(filepath.Join(idx.dir, vs.ID, "store.json"), vs); err != nil {
+			return err
+		}
+	}
+
+	if entry, ok := idx.stores[vs.ID]; ok {
+		entry.store = *vs
+	} else {
+		idx.stores[vs.ID] = &storeEntry{store: *vs, files: make(map[string]*fileEntry)}
+	}
+	return nil
+}
+
+// GetStore returns the vector store with the given ID
+func (idx *FlatIndex) GetStore(id string) (*VectorStore, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	entry, ok := idx.stores[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	vs := entry.store
+	return &vs, nil
+}
+
+// ListStores returns the vector stores owned by alias, oldest first
+func (idx *FlatIndex) ListStores(alias string) ([]*VectorStore, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var result []*VectorStore
+	for _, entry := range idx.stores {
+		if entry.store.Alias == alias {
+			vs := entry.store
+			result = append(result, &vs)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// DeleteStore removes a vector store and its files
+func (idx *FlatIndex) DeleteStore(id string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if _, ok := idx.stores[id]; !ok {
+		return ErrNotFound
+	}
+	if idx.dir != "" {
+		if err := os.RemoveAll(filepath.Join(idx.dir, id)); err != nil {
+			return fmt.Errorf("failed to delete vector store: %w", err)
+		}
+	}
+	delete(idx.stores, id)
+	return nil
+}
+
+// AddFile stores a file's chunks, whose embeddings must already be unit length (see NewChunk)
+func (idx *FlatIndex) AddFile(file *File, chunks []Chunk) error {
+	if !fsutil.ValidID(file.ID) {
+		return fmt.Errorf("invalid file id: %q", file.ID)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	entry, ok := idx.stores[file.StoreID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	fe := &fileEntry{File: *file, Chunks: chunks}
+
+	if idx.dir != "" {
+		if err := writeJSON(idx.filePath(file.StoreID, file.ID), fe); err != nil {
+			return err
+		}
+	}
+	entry.files[file.ID] = fe
+	return nil
+}
+
+// GetFile returns a file in a vector store
+func (idx *FlatIndex) GetFile(storeID, fileID string) (*File, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	entry, ok := idx.stores[storeID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	fe, ok := entry.files[fileID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	file := fe.File
+	return &file, nil
+}
+
+// ListFiles returns the files in a vector store, oldest first
+func (idx *FlatIndex) ListFiles(storeID string) ([]*File, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	entry, ok := idx.stores[storeID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	result := make([]*File, 0, len(entry.files))
+	for _, fe := range entry.files {
+		file := fe.File
+		result = append(result, &file)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// DeleteFile removes a file from a vector store
+func (idx *FlatIndex) DeleteFile(storeID, fileID string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	entry, ok := idx.stores[storeID]
+	if !ok {
+		return ErrNotFound
+	}
+	if _, ok := entry.files[fileID]; !ok {
+		return ErrNotFound
+	}
+	if idx.dir != "" {
+		if err := os.Remove(idx.filePath(storeID, fileID)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete file: %w", err)
+		}
+	}
+	delete(entry.files, fileID)
+	return nil
+}
+
+// Search scores every chunk in the given stores against query
+func (idx *FlatIndex) Search(storeIDs []string, query []float64, limit int) ([]SearchResult, error) {
+	q := vector.Normalize(query)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var results []SearchResult
+	for _, id := range storeIDs {
+		entry, ok := idx.stores[id]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		for _, fe := range entry.files {
+			for _, chunk := range fe.Chunks {
+				results = append(results, SearchResult{
+					File:  fe.File,
+					Text:  chunk.Text,
+					Score: dot(q, chunk.Embedding),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// load reads all stores and files from disk, skipping entries that cannot be decoded
+func (idx *FlatIndex) load() error {
+	entries, err := os.ReadDir(idx.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read vector store directory: %w", err)
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() || !fsutil.ValidID(e.Name()) {
+			continue
+		}
+
+		var vs VectorStore
+		if err := readJSON(filepath.Join(idx.dir, e.Name(), "store.json"), &vs); err != nil {
+			continue
+		}
+		entry := &storeEntry{store: vs, files: make(map[string]*fileEntry)}
+
+		files, _ := filepath.Glob(filepath.Join(idx.dir, e.Name(), "files", "*.json"))
+		for _, path := range files {
+			var fe fileEntry
+			if err := readJSON(path, &fe); err != nil {
+				continue
+			}
+			entry.files[fe.File.ID] = &fe
+		}
+		idx.stores[vs.ID] = entry
+	}
+	return nil
+}
+
+func (idx *FlatIndex) filePath(storeID, fileID string) string {
+	return filepath.Join(idx.dir, storeID, "files", fileID+".json")
+}
+
+// NewChunk converts an embedding to the stored unit-length float32 form
+func NewChunk(text string, embedding []float64) Chunk {
+	normalized := vector.Normalize(embedding)
+	stored := make([]float32, len(normalized))
+	for i, x := range normalized {
+		stored[i] = float32(x)
+	}
+	return Chunk{Text: text, Embedding: stored}
+}
+
+func dot(q []float64, v []float32) float64 {
+	var sum float64
+	for i := range min(len(q), len(v)) {
+		sum += q[i] * float64(v[i])
+	}
+	return sum
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeJSON encodes v to a temporary file and renames it into place
+func writeJSON(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to rename file: %w", err)
+	}
+	return nil
+}
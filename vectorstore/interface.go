@@ -0,0 +1,77 @@
+package vectorstore
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a vector store or file does not exist
+var ErrNotFound = errors.New("not found")
+
+// VectorStore is a named collection of embedded documents owned by an API key alias
+type VectorStore struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Alias     string            `json:"alias"`
+	Model     string            `json:"model"` // Embedding model used for every chunk in the store
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// File is a document added to a vector store
+type File struct {
+	ID         string                 `json:"id"`
+	StoreID    string                 `json:"vector_store_id"`
+	Filename   string                 `json:"filename"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	UsageBytes int64                  `json:"usage_bytes"`
+	ChunkCount int                    `json:"chunk_count"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// Chunk is a window of a file's text with its embedding
+type Chunk struct {
+	Text      string    `json:"text"`
+	Embedding []float32 `json:"embedding"` // Unit length, so a dot product gives cosine similarity
+}
+
+// SearchResult is a chunk matching a query, scored by cosine similarity
+type SearchResult struct {
+	File  File
+	Text  string
+	Score float64
+}
+
+// Index defines the interface for storing and searching vector stores
+// This allows for different implementations (flat, HNSW, external databases, etc.)
+type Index interface {
+	// SaveStore creates a vector store or replaces its metadata
+	SaveStore(vs *VectorStore) error
+
+	// GetStore returns the vector store with the given ID, or ErrNotFound
+	GetStore(id string) (*VectorStore, error)
+
+	// ListStores returns the vector stores owned by alias, oldest first
+	ListStores(alias string) ([]*VectorStore, error)
+
+	// DeleteStore removes a vector store and all of its files, or returns ErrNotFound
+	DeleteStore(id string) error
+
+	// AddFile stores a file's chunks, replacing any file with the same ID
+	AddFile(file *File, chunks []Chunk) error
+
+	// GetFile returns a file in a vector store, or ErrNotFound
+	GetFile(storeID, fileID string) (*File, error)
+
+	// ListFiles returns the files in a vector store, oldest first
+	ListFiles(storeID string) ([]*File, error)
+
+	// DeleteFile removes a file from a vector store, or returns ErrNotFound
+	DeleteFile(storeID, fileID string) error
+
+	// Search returns up to limit chunks from the given stores closest to query
+	Search(storeIDs []string, query []float64, limit int) ([]SearchResult, error)
+}
+
+// Ensure implementations satisfy Index
+var _ Index = (*FlatIndex)(nil)